@@ -2,15 +2,21 @@ package grappa
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
-	"strings"
+	"fmt"
+	"sync"
+	"sync/atomic"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/google/cel-go/cel"
 	"github.com/google/uuid"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 
 	"github.com/stevecallear/grappa/internal/convert"
+	"github.com/stevecallear/grappa/internal/scope"
+	"github.com/stevecallear/grappa/internal/validate"
 	"github.com/stevecallear/grappa/proto/grappapb"
 )
 
@@ -22,8 +28,9 @@ type (
 
 	// Authorizor represents a jwt authorizor
 	Authorizor struct {
-		opts  Options
-		rules []rule
+		opts   Options
+		mu     sync.Mutex
+		router atomic.Value
 	}
 
 	// Context represents a request context
@@ -31,11 +38,15 @@ type (
 		ID         string
 		FullMethod string
 		Rule       *grappapb.Rule
+		scope      scope.Expr
+		condition  cel.Program
+		verified   *verified
 	}
 
-	rule struct {
-		rule    *grappapb.Rule
-		matchFn func(pattern string) bool
+	serverStream struct {
+		grpc.ServerStream
+		ctx      context.Context
+		verifyFn func(interface{}) error
 	}
 )
 
@@ -47,17 +58,61 @@ func New(optFns ...func(*Options)) *Authorizor {
 		fn(&o)
 	}
 
-	return &Authorizor{
-		opts:  o,
-		rules: []rule{},
-	}
+	a := &Authorizor{opts: o}
+	a.router.Store(newRouter())
+
+	return a
 }
 
 // Register registers the rule for the specified method pattern
+// patterns can include a trailing wildcard, e.g. /package.Service/*
+// the most specific matching pattern is used regardless of registration order
+// Register panics if the rule is invalid or a different rule is already registered for the pattern
 func (a *Authorizor) Register(pattern string, r *grappapb.Rule) {
-	a.rules = append(a.rules, rule{
-		rule:    r,
-		matchFn: newMatcher(pattern),
+	if err := a.Load(map[string]*grappapb.Rule{pattern: r}); err != nil {
+		panic(err)
+	}
+}
+
+// Unregister removes the rule for the specified method pattern
+func (a *Authorizor) Unregister(pattern string) {
+	a.update(func(rt *router) error {
+		rt.remove(pattern)
+		return nil
+	})
+}
+
+// Load atomically registers the specified rules, keyed by method pattern
+// if any rule conflicts with an existing rule, then none of the rules are registered
+func (a *Authorizor) Load(rules map[string]*grappapb.Rule) error {
+	return a.update(func(rt *router) error {
+		return a.addRules(rt, rules)
+	})
+}
+
+// ReplaceRules atomically replaces all registered rules with the specified rules
+func (a *Authorizor) ReplaceRules(rules map[string]*grappapb.Rule) error {
+	rt := newRouter()
+	if err := a.addRules(rt, rules); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.router.Store(rt)
+	return nil
+}
+
+// Reload atomically unregisters the specified patterns and registers the specified rules
+// if any rule conflicts with a remaining rule, then the existing rules are left unchanged
+func (a *Authorizor) Reload(patterns []string, rules map[string]*grappapb.Rule) error {
+	return a.update(func(rt *router) error {
+		for _, p := range patterns {
+			rt.remove(p)
+		}
+
+		return a.addRules(rt, rules)
 	})
 }
 
@@ -68,9 +123,36 @@ func (a *Authorizor) UnaryInterceptor(ctx context.Context, req interface{}, info
 		return nil, err
 	}
 
+	if err = a.verifyRequest(ctx, req); err != nil {
+		return nil, err
+	}
+
 	return handler(ctx, req)
 }
 
+// StreamInterceptor is a stream interceptor func
+// the rule policy is evaluated with a nil request before the handler is called,
+// so that it applies to streams that do not receive messages, while field bindings,
+// conditions and policies are evaluated for each received message
+func (a *Authorizor) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	if err = a.evaluatePolicy(ctx, nil); err != nil {
+		return err
+	}
+
+	return handler(srv, &serverStream{
+		ServerStream: ss,
+		ctx:          ctx,
+		verifyFn: func(m interface{}) error {
+			return a.verifyRequest(ctx, m)
+		},
+	})
+}
+
 func (a *Authorizor) authorize(ctx context.Context, fullMethod string) (context.Context, error) {
 	rctx := Context{
 		ID:         uuid.NewString(),
@@ -82,7 +164,9 @@ func (a *Authorizor) authorize(ctx context.Context, fullMethod string) (context.
 		return nil, a.opts.ErrorFn(rctx, err)
 	}
 
-	rctx.Rule = rule
+	rctx.Rule = rule.rule
+	rctx.scope = rule.scope
+	rctx.condition = rule.condition
 
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
@@ -92,20 +176,12 @@ func (a *Authorizor) authorize(ctx context.Context, fullMethod string) (context.
 	token, ok := a.opts.TokenFn(rctx, md)
 	if !ok {
 		if rctx.Rule.AllowAnonymous {
-			return ctx, nil
+			return newContext(ctx, rctx, nil, nil), nil
 		}
 		return nil, a.opts.ErrorFn(rctx, errors.New("invalid authorization"))
 	}
 
-	claims := jwt.MapClaims{}
-	_, err = jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
-		k, err := a.opts.KeyFn(rctx, t)
-		if err != nil {
-			return nil, err
-		}
-
-		return k, nil
-	})
+	claims, err := a.parseClaims(ctx, rctx, token)
 	if err != nil {
 		return nil, a.opts.ErrorFn(rctx, err)
 	}
@@ -114,30 +190,170 @@ func (a *Authorizor) authorize(ctx context.Context, fullMethod string) (context.
 		return nil, a.opts.ErrorFn(rctx, err)
 	}
 
-	return metadata.NewIncomingContext(ctx, a.captureClaims(md, claims)), nil
+	var custom jwt.Claims
+	if a.opts.ClaimsFn != nil {
+		if custom, err = a.customClaims(rctx, token, claims); err != nil {
+			return nil, a.opts.ErrorFn(rctx, err)
+		}
+	}
+
+	ctx = metadata.NewIncomingContext(ctx, a.captureClaims(md, claims))
+	return newContext(ctx, rctx, claims, custom), nil
 }
 
-func (a *Authorizor) getRule(fullMethod string) (*grappapb.Rule, error) {
-	for _, r := range a.rules {
-		if r.matchFn(fullMethod) {
-			return r.rule, nil
-		}
+// verifyRequest verifies the request message against the authorized rule
+// field bindings are verified, then the condition and rule policy are evaluated
+func (a *Authorizor) verifyRequest(ctx context.Context, req interface{}) error {
+	if err := a.verifyFieldBindings(ctx, req); err != nil {
+		return err
+	}
+
+	if err := a.evaluateCondition(ctx, req); err != nil {
+		return err
+	}
+
+	return a.evaluatePolicy(ctx, req)
+}
+
+// update applies fn to a copy of the current rules, storing the result
+// requests that are in progress continue to use the previous rules
+func (a *Authorizor) update(fn func(*router) error) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rt := a.router.Load().(*router).clone()
+	if err := fn(rt); err != nil {
+		return err
+	}
+
+	a.router.Store(rt)
+	return nil
+}
+
+func (a *Authorizor) getRule(fullMethod string) (*rule, error) {
+	if r, ok := a.router.Load().(*router).match(fullMethod); ok {
+		return r, nil
 	}
 
 	if a.opts.Optional {
-		return &grappapb.Rule{AllowAnonymous: true}, nil
+		return &rule{pattern: fullMethod, rule: &grappapb.Rule{AllowAnonymous: true}}, nil
 	}
 
 	return nil, errors.New("rule not found")
 }
 
+// addRules adds the rules to the router, returning an error if a rule is
+// invalid, conflicts, specifies a policy that has not been configured or has an
+// invalid condition
+// scope expressions and conditions are compiled once, rather than per request
+func (a *Authorizor) addRules(rt *router, rules map[string]*grappapb.Rule) error {
+	for p, r := range rules {
+		if err := validate.Rule(r); err != nil {
+			return fmt.Errorf("invalid rule for pattern %s: %v", p, err)
+		}
+
+		if n := r.GetPolicy(); n != "" {
+			if _, ok := a.opts.Policies[n]; !ok {
+				return fmt.Errorf("policy not found for pattern %s: %s", p, n)
+			}
+		}
+
+		nr := &rule{pattern: p, rule: r}
+
+		var err error
+		if e := r.GetScopeExpression(); e != "" {
+			if nr.scope, err = scope.Parse(e); err != nil {
+				return fmt.Errorf("invalid scope expression for pattern %s: %v", p, err)
+			}
+		}
+
+		if nr.condition, err = compileCondition(p, r); err != nil {
+			return err
+		}
+
+		if err = rt.add(nr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifyClaims verifies the claims using the configured verifiers, returning
+// an error if a rule requirement was not verified by any of them
 func (a *Authorizor) verifyClaims(ctx Context, c jwt.MapClaims) error {
+	ctx.verified = new(verified)
 	for _, fn := range a.opts.ClaimsVerifiers {
 		if err := fn(ctx, c); err != nil {
 			return err
 		}
 	}
-	return nil
+
+	return ctx.verified.check(ctx.Rule)
+}
+
+// parseClaims returns the verified token claims, either by parsing the token
+// as a jwt or using the configured introspection func
+func (a *Authorizor) parseClaims(ctx context.Context, rctx Context, token string) (jwt.MapClaims, error) {
+	if a.opts.IntrospectFn != nil {
+		c, err := a.opts.IntrospectFn(ctx, rctx, token)
+		if err != nil {
+			return nil, err
+		}
+
+		// introspection results can be cached, so time based claims are always verified
+		if err = c.Valid(); err != nil {
+			return nil, err
+		}
+
+		return c, nil
+	}
+
+	claims := jwt.MapClaims{}
+	p := jwt.Parser{ValidMethods: a.opts.Algorithms}
+	_, err := p.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		k, err := a.opts.KeyFn(rctx, t)
+		if err != nil {
+			return nil, err
+		}
+
+		return k, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (a *Authorizor) customClaims(ctx Context, token string, claims jwt.MapClaims) (jwt.Claims, error) {
+	c := a.opts.ClaimsFn()
+	if a.opts.IntrospectFn != nil {
+		// introspected tokens are opaque, so the verified claims are decoded instead
+		b, err := json.Marshal(claims)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = json.Unmarshal(b, c); err != nil {
+			return nil, err
+		}
+	} else if _, _, err := new(jwt.Parser).ParseUnverified(token, c); err != nil {
+		// the token signature has already been verified at this point
+		return nil, err
+	}
+
+	if err := c.Valid(); err != nil {
+		return nil, err
+	}
+
+	for _, fn := range a.opts.CustomClaimsVerifiers {
+		if err := fn(ctx, c); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
 }
 
 func (a *Authorizor) captureClaims(md metadata.MD, c jwt.MapClaims) metadata.MD {
@@ -151,15 +367,16 @@ func (a *Authorizor) captureClaims(md metadata.MD, c jwt.MapClaims) metadata.MD
 	return md
 }
 
-func newMatcher(pattern string) func(string) bool {
-	if strings.HasSuffix(pattern, "*") {
-		pre := strings.ToLower(pattern[:len(pattern)-1])
-		return func(v string) bool {
-			return strings.HasPrefix(strings.ToLower(v), pre)
-		}
-	}
+// Context returns the authorized stream context
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
 
-	return func(v string) bool {
-		return strings.EqualFold(v, pattern)
+// RecvMsg receives a message and verifies it against the rule
+func (s *serverStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
 	}
+
+	return s.verifyFn(m)
 }
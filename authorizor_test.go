@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -150,6 +152,29 @@ func TestNew_UnaryInterceptor(t *testing.T) {
 			},
 			err: true,
 		},
+		{
+			name: "should reject tokens signed with algorithms that are not allowed",
+			options: func(o *grappa.Options) {
+				o.TokenFn = func(grappa.Context, metadata.MD) (string, bool) {
+					return newHMAC([]byte("secretkey"), map[string]interface{}{
+						"sub": "subject",
+						"exp": now.Add(1 * time.Hour).Unix(),
+					}), true
+				}
+				o.KeyFn = func(grappa.Context, *jwt.Token) (interface{}, error) {
+					return []byte("secretkey"), nil
+				}
+				o.Algorithms = []string{"RS256"}
+			},
+			setup: func(a *grappa.Authorizor) {
+				a.Register(info.FullMethod, &grappapb.Rule{})
+			},
+			ctx: metadata.NewIncomingContext(context.Background(), metadata.MD{}),
+			handler: func(context.Context, interface{}) (interface{}, error) {
+				return nil, nil
+			},
+			err: true,
+		},
 		{
 			name: "should execute claims verification funcs",
 			options: func(o *grappa.Options) {
@@ -248,6 +273,113 @@ func TestNew_UnaryInterceptor(t *testing.T) {
 	}
 }
 
+func TestNew_StreamInterceptor(t *testing.T) {
+	now := time.Now().UTC()
+	info := &grpc.StreamServerInfo{
+		FullMethod: "/package.Service/Method",
+	}
+
+	tests := []struct {
+		name    string
+		options func(*grappa.Options)
+		setup   func(*grappa.Authorizor)
+		ctx     context.Context
+		handler grpc.StreamHandler
+		err     bool
+	}{
+		{
+			name:    "should require a token for missing rules if the authorizor is not configured to be optional",
+			options: func(o *grappa.Options) {},
+			setup:   func(a *grappa.Authorizor) {},
+			ctx:     metadata.NewIncomingContext(context.Background(), metadata.MD{}),
+			handler: func(interface{}, grpc.ServerStream) error {
+				t.Errorf("got handler invocation, expected no invocation")
+				return nil
+			},
+			err: true,
+		},
+		{
+			name:    "should not require a token if the rule is configured to allow anonymous",
+			options: func(o *grappa.Options) {},
+			setup: func(a *grappa.Authorizor) {
+				a.Register("/package.Service/*", &grappapb.Rule{
+					AllowAnonymous: true,
+				})
+			},
+			ctx: metadata.NewIncomingContext(context.Background(), metadata.MD{}),
+			handler: func(interface{}, grpc.ServerStream) error {
+				return nil
+			},
+		},
+		{
+			name: "should execute claims verification funcs",
+			options: func(o *grappa.Options) {
+				o.TokenFn = func(grappa.Context, metadata.MD) (string, bool) {
+					return newHMAC([]byte("secretkey"), map[string]interface{}{
+						"sub": "subject",
+						"exp": now.Add(1 * time.Hour).Unix(),
+					}), true
+				}
+				o.KeyFn = func(grappa.Context, *jwt.Token) (interface{}, error) {
+					return []byte("secretkey"), nil
+				}
+				o.ClaimsVerifiers = []grappa.VerifyFunc{
+					func(grappa.Context, jwt.MapClaims) error {
+						return errors.New("error")
+					},
+				}
+			},
+			setup: func(a *grappa.Authorizor) {
+				a.Register(info.FullMethod, new(grappapb.Rule))
+			},
+			ctx: metadata.NewIncomingContext(context.Background(), metadata.MD{}),
+			handler: func(interface{}, grpc.ServerStream) error {
+				t.Errorf("got handler invocation, expected no invocation")
+				return nil
+			},
+			err: true,
+		},
+		{
+			name: "should copy mapped claims into the stream context metadata",
+			options: func(o *grappa.Options) {
+				o.TokenFn = func(grappa.Context, metadata.MD) (string, bool) {
+					return newHMAC([]byte("secretkey"), map[string]interface{}{
+						"sub": "subject",
+						"exp": now.Add(1 * time.Hour).Unix(),
+					}), true
+				}
+				o.KeyFn = func(grappa.Context, *jwt.Token) (interface{}, error) {
+					return []byte("secretkey"), nil
+				}
+				o.ClaimsMap = map[string]string{
+					"sub": "auth.sub",
+				}
+			},
+			setup: func(a *grappa.Authorizor) {
+				a.Register(info.FullMethod, new(grappapb.Rule))
+			},
+			ctx: metadata.NewIncomingContext(context.Background(), metadata.MD{}),
+			handler: func(_ interface{}, ss grpc.ServerStream) error {
+				md, _ := metadata.FromIncomingContext(ss.Context())
+				if act, exp := md.Get("auth.sub"), []string{"subject"}; !reflect.DeepEqual(act, exp) {
+					t.Errorf("got %v, expected %v", act, exp)
+				}
+				return nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := grappa.New(tt.options)
+			tt.setup(sut)
+
+			err := sut.StreamInterceptor(nil, &serverStream{ctx: tt.ctx}, info, tt.handler)
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
+
 func TestRegister(t *testing.T) {
 	info := &grpc.UnaryServerInfo{
 		FullMethod: "/package.Service/Method",
@@ -292,3 +424,383 @@ func TestRegister(t *testing.T) {
 		})
 	}
 }
+
+func TestRegister_Precedence(t *testing.T) {
+	anon := &grappapb.Rule{AllowAnonymous: true}
+	auth := &grappapb.Rule{AllowAnonymous: false}
+
+	tests := []struct {
+		name  string
+		setup func(*grappa.Authorizor)
+		err   bool
+	}{
+		{
+			name: "should prefer exact patterns to earlier wildcard patterns",
+			setup: func(a *grappa.Authorizor) {
+				a.Register("/package.Service/*", auth)
+				a.Register("/package.Service/Method", anon)
+			},
+		},
+		{
+			name: "should prefer exact patterns to later wildcard patterns",
+			setup: func(a *grappa.Authorizor) {
+				a.Register("/package.Service/Method", auth)
+				a.Register("/package.Service/*", anon)
+			},
+			err: true,
+		},
+		{
+			name: "should prefer longer wildcard patterns",
+			setup: func(a *grappa.Authorizor) {
+				a.Register("*", auth)
+				a.Register("/package.*", auth)
+				a.Register("/package.Service/Meth*", anon)
+				a.Register("/package.Service/*", auth)
+			},
+		},
+		{
+			name: "should match patterns regardless of case",
+			setup: func(a *grappa.Authorizor) {
+				a.Register("/PACKAGE.SERVICE/*", anon)
+			},
+		},
+		{
+			name: "should match global wildcard patterns",
+			setup: func(a *grappa.Authorizor) {
+				a.Register("/other.Service/*", auth)
+				a.Register("*", anon)
+			},
+		},
+		{
+			name: "should not match longer wildcard patterns",
+			setup: func(a *grappa.Authorizor) {
+				a.Register("/package.Service/MethodA*", anon)
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := grappa.New()
+			tt.setup(sut)
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{})
+			info := &grpc.UnaryServerInfo{FullMethod: "/package.Service/Method"}
+
+			_, err := sut.UnaryInterceptor(ctx, nil, info, func(context.Context, interface{}) (interface{}, error) {
+				return "response", nil
+			})
+
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
+
+func TestRegister_Conflict(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*grappa.Authorizor)
+		panic bool
+	}{
+		{
+			name: "should allow duplicate registration of equal rules",
+			setup: func(a *grappa.Authorizor) {
+				a.Register("/package.Service/Method", &grappapb.Rule{RequireScope: []string{"scope"}})
+				a.Register("/package.service/method", &grappapb.Rule{RequireScope: []string{"scope"}})
+			},
+		},
+		{
+			name: "should panic if an exact pattern conflicts",
+			setup: func(a *grappa.Authorizor) {
+				a.Register("/package.Service/Method", &grappapb.Rule{RequireScope: []string{"scope"}})
+				a.Register("/package.Service/Method", &grappapb.Rule{AllowAnonymous: true})
+			},
+			panic: true,
+		},
+		{
+			name: "should panic if a wildcard pattern conflicts",
+			setup: func(a *grappa.Authorizor) {
+				a.Register("/package.Service/*", &grappapb.Rule{RequireScope: []string{"scope"}})
+				a.Register("/package.SERVICE/*", &grappapb.Rule{AllowAnonymous: true})
+			},
+			panic: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); (r != nil) != tt.panic {
+					t.Errorf("got %v, expected panic %v", r, tt.panic)
+				}
+			}()
+
+			tt.setup(grappa.New())
+		})
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		err     bool
+	}{
+		{
+			name:    "should remove exact patterns",
+			pattern: "/package.service/method",
+			err:     true,
+		},
+		{
+			name:    "should remove wildcard patterns",
+			pattern: "/package.Service/*",
+		},
+		{
+			name:    "should ignore unknown patterns",
+			pattern: "/package.Service/Meth*",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := grappa.New()
+			sut.Register("/package.Service/Method", &grappapb.Rule{AllowAnonymous: true})
+			sut.Register("/package.Service/*", &grappapb.Rule{AllowAnonymous: false})
+
+			sut.Unregister(tt.pattern)
+
+			err := invokeAnonymous(sut, "/package.Service/Method")
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules map[string]*grappapb.Rule
+		err   bool
+		exp   map[string]bool
+	}{
+		{
+			name: "should not register any rules if a rule conflicts",
+			rules: map[string]*grappapb.Rule{
+				"/package.Service/MethodA": {AllowAnonymous: true},
+				"/package.Service/MethodB": {AllowAnonymous: true},
+			},
+			err: true,
+			exp: map[string]bool{
+				"/package.Service/MethodA": false,
+				"/package.Service/MethodB": false,
+			},
+		},
+		{
+			name: "should return an error if a scope expression is invalid",
+			rules: map[string]*grappapb.Rule{
+				"/package.Service/MethodA": {AllowAnonymous: true},
+				"/package.Service/*":       {ScopeExpression: "read &&"},
+			},
+			err: true,
+			exp: map[string]bool{
+				"/package.Service/MethodA": false,
+			},
+		},
+		{
+			name: "should return an error if a claim requirement is invalid",
+			rules: map[string]*grappapb.Rule{
+				"/package.Service/MethodA": {AllowAnonymous: true},
+				"/package.Service/*": {RequireClaim: []*grappapb.ClaimRequirement{
+					{Claim: "tenant", Operator: grappapb.ClaimRequirement_EQUALS},
+				}},
+			},
+			err: true,
+			exp: map[string]bool{
+				"/package.Service/MethodA": false,
+			},
+		},
+		{
+			name: "should register all rules",
+			rules: map[string]*grappapb.Rule{
+				"/package.Service/MethodA": {AllowAnonymous: true},
+				"/package.Service/*":       {AllowAnonymous: true},
+			},
+			exp: map[string]bool{
+				"/package.Service/MethodA": true,
+				"/package.Service/MethodB": false,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := grappa.New()
+			sut.Register("/package.Service/MethodB", &grappapb.Rule{RequireScope: []string{"scope"}})
+
+			err := sut.Load(tt.rules)
+			assertErrorExists(t, err, tt.err)
+
+			for m, exp := range tt.exp {
+				if act := invokeAnonymous(sut, m) == nil; act != exp {
+					t.Errorf("got %v, expected %v for %s", act, exp, m)
+				}
+			}
+		})
+	}
+}
+
+func TestReplaceRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules map[string]*grappapb.Rule
+		err   bool
+		exp   map[string]bool
+	}{
+		{
+			name: "should not replace the rules if a rule conflicts",
+			rules: map[string]*grappapb.Rule{
+				"/package.Service/MethodB": {AllowAnonymous: true},
+				"/package.service/methodb": {AllowAnonymous: false},
+			},
+			err: true,
+			exp: map[string]bool{
+				"/package.Service/MethodA": true,
+				"/package.Service/MethodB": false,
+			},
+		},
+		{
+			name: "should replace all rules",
+			rules: map[string]*grappapb.Rule{
+				"/package.Service/MethodB": {AllowAnonymous: true},
+			},
+			exp: map[string]bool{
+				"/package.Service/MethodA": false,
+				"/package.Service/MethodB": true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := grappa.New()
+			sut.Register("/package.Service/MethodA", &grappapb.Rule{AllowAnonymous: true})
+
+			err := sut.ReplaceRules(tt.rules)
+			assertErrorExists(t, err, tt.err)
+
+			for m, exp := range tt.exp {
+				if act := invokeAnonymous(sut, m) == nil; act != exp {
+					t.Errorf("got %v, expected %v for %s", act, exp, m)
+				}
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		rules    map[string]*grappapb.Rule
+		err      bool
+		exp      map[string]bool
+	}{
+		{
+			name: "should not update the rules if a rule conflicts",
+			patterns: []string{
+				"/package.Service/MethodA",
+			},
+			rules: map[string]*grappapb.Rule{
+				"/package.Service/MethodA": {AllowAnonymous: false},
+				"/package.Service/MethodB": {AllowAnonymous: true},
+			},
+			err: true,
+			exp: map[string]bool{
+				"/package.Service/MethodA": true,
+				"/package.Service/MethodB": false,
+			},
+		},
+		{
+			name: "should replace unregistered patterns",
+			patterns: []string{
+				"/package.Service/MethodA",
+			},
+			rules: map[string]*grappapb.Rule{
+				"/package.Service/MethodA": {AllowAnonymous: false},
+			},
+			exp: map[string]bool{
+				"/package.Service/MethodA": false,
+				"/package.Service/MethodB": false,
+			},
+		},
+		{
+			name: "should retain other rules",
+			patterns: []string{
+				"/package.Service/MethodB",
+			},
+			rules: map[string]*grappapb.Rule{
+				"/package.Service/MethodB": {AllowAnonymous: true},
+			},
+			exp: map[string]bool{
+				"/package.Service/MethodA": true,
+				"/package.Service/MethodB": true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := grappa.New()
+			sut.Register("/package.Service/MethodA", &grappapb.Rule{AllowAnonymous: true})
+			sut.Register("/package.Service/MethodB", &grappapb.Rule{RequireScope: []string{"scope"}})
+
+			err := sut.Reload(tt.patterns, tt.rules)
+			assertErrorExists(t, err, tt.err)
+
+			for m, exp := range tt.exp {
+				if act := invokeAnonymous(sut, m) == nil; act != exp {
+					t.Errorf("got %v, expected %v for %s", act, exp, m)
+				}
+			}
+		})
+	}
+}
+
+func TestRegister_Concurrent(t *testing.T) {
+	t.Run("should support registration while serving requests", func(t *testing.T) {
+		sut := grappa.New()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+
+			go func(i int) {
+				defer wg.Done()
+				sut.Register(fmt.Sprintf("/package.Service/Method%d", i), &grappapb.Rule{AllowAnonymous: true})
+			}(i)
+
+			go func(i int) {
+				defer wg.Done()
+				invokeAnonymous(sut, fmt.Sprintf("/package.Service/Method%d", i))
+			}(i)
+		}
+
+		wg.Wait()
+
+		for i := 0; i < 10; i++ {
+			err := invokeAnonymous(sut, fmt.Sprintf("/package.Service/Method%d", i))
+			assertErrorExists(t, err, false)
+		}
+	})
+}
+
+func invokeAnonymous(a *grappa.Authorizor, fullMethod string) error {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{})
+	info := &grpc.UnaryServerInfo{FullMethod: fullMethod}
+
+	_, err := a.UnaryInterceptor(ctx, nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return nil, nil
+	})
+
+	return err
+}
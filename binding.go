@@ -0,0 +1,152 @@
+package grappa
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/stevecallear/grappa/internal/convert"
+	"github.com/stevecallear/grappa/proto/grappapb"
+)
+
+// verifyFieldBindings verifies the rule field bindings against the request message
+// bindings are always denied for anonymous requests, as there are no claims to bind to
+func (a *Authorizor) verifyFieldBindings(ctx context.Context, req interface{}) error {
+	rctx, _ := FromContext(ctx)
+
+	bs := rctx.Rule.GetBindField()
+	if len(bs) < 1 {
+		return nil
+	}
+
+	m, ok := req.(proto.Message)
+	if !ok {
+		return a.opts.ErrorFn(rctx, fmt.Errorf("invalid request message type: %T", req))
+	}
+
+	claims, _ := ClaimsFromContext(ctx)
+
+	for _, b := range bs {
+		if err := verifyFieldBinding(m.ProtoReflect(), claims, b); err != nil {
+			return a.opts.ErrorFn(rctx, err)
+		}
+	}
+
+	return nil
+}
+
+func verifyFieldBinding(m protoreflect.Message, c jwt.MapClaims, b *grappapb.FieldBinding) error {
+	vs, err := fieldValues(m, b.GetField())
+	if err != nil {
+		return err
+	}
+
+	cvs, ok := bindingClaimValues(c, b)
+	if !ok || len(vs) < 1 {
+		return fmt.Errorf("invalid %s field", b.GetField())
+	}
+
+	for _, v := range vs {
+		if !containsStr(cvs, v) {
+			return fmt.Errorf("invalid %s field", b.GetField())
+		}
+	}
+
+	return nil
+}
+
+// fieldValues returns the string values of the field at the dotted path
+// unset singular fields return no values
+func fieldValues(m protoreflect.Message, path string) ([]string, error) {
+	ns := strings.Split(path, ".")
+	for i, n := range ns {
+		fd := m.Descriptor().Fields().ByName(protoreflect.Name(n))
+		if fd == nil || fd.IsMap() {
+			return nil, fmt.Errorf("invalid field path: %s", path)
+		}
+
+		if i < len(ns)-1 {
+			if fd.Message() == nil || fd.IsList() {
+				return nil, fmt.Errorf("invalid field path: %s", path)
+			}
+
+			m = m.Get(fd).Message()
+			continue
+		}
+
+		if fd.Message() != nil {
+			return nil, fmt.Errorf("invalid field path: %s", path)
+		}
+
+		if fd.IsList() {
+			l := m.Get(fd).List()
+			vs := make([]string, l.Len())
+			for j := range vs {
+				vs[j] = l.Get(j).String()
+			}
+			return vs, nil
+		}
+
+		if !m.Has(fd) {
+			return nil, nil
+		}
+
+		return []string{m.Get(fd).String()}, nil
+	}
+
+	return nil, nil
+}
+
+// bindingClaimValues returns the claim values that the field can be bound to
+// EQUALS requires a single value claim, while IN accepts array or space delimited claims
+func bindingClaimValues(c jwt.MapClaims, b *grappapb.FieldBinding) ([]string, bool) {
+	v, ok := getClaim(c, b.GetClaim())
+	if !ok {
+		return nil, false
+	}
+
+	switch b.GetOperator() {
+	case grappapb.FieldBinding_EQUALS:
+		switch v.(type) {
+		case nil, []interface{}, []string, map[string]interface{}:
+			return nil, false
+		default:
+			return []string{convert.ToString(v)}, true
+		}
+	case grappapb.FieldBinding_IN:
+		switch tv := v.(type) {
+		case string:
+			return strings.Fields(tv), true
+		case []string:
+			return tv, true
+		case []interface{}:
+			ss := make([]string, 0, len(tv))
+			for _, ev := range tv {
+				switch ev.(type) {
+				case []interface{}, map[string]interface{}:
+					return nil, false
+				}
+				ss = append(ss, convert.ToString(ev))
+			}
+			return ss, true
+		default:
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+}
+
+func containsStr(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
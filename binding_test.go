@@ -0,0 +1,224 @@
+package grappa_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/stevecallear/grappa"
+	"github.com/stevecallear/grappa/proto/grappapb"
+)
+
+func TestFieldBinding_UnaryInterceptor(t *testing.T) {
+	claims := jwt.MapClaims{
+		"sub":       "subject",
+		"tenant_id": 123,
+		"groups":    []string{"a", "b"},
+		"realm":     map[string]interface{}{"id": "realm"},
+	}
+
+	tests := []struct {
+		name     string
+		bindings []*grappapb.FieldBinding
+		claims   jwt.MapClaims
+		req      interface{}
+		err      bool
+	}{
+		{
+			name:     "should return an error if the request is not a proto message",
+			bindings: []*grappapb.FieldBinding{{Field: "full_method", Claim: "sub"}},
+			claims:   claims,
+			req:      new(string),
+			err:      true,
+		},
+		{
+			name:     "should return an error if the field does not exist",
+			bindings: []*grappapb.FieldBinding{{Field: "invalid", Claim: "sub"}},
+			claims:   claims,
+			req:      &grappapb.MethodRule{FullMethod: "subject"},
+			err:      true,
+		},
+		{
+			name:     "should return an error if the field is a message",
+			bindings: []*grappapb.FieldBinding{{Field: "rule", Claim: "sub"}},
+			claims:   claims,
+			req:      &grappapb.MethodRule{Rule: &grappapb.Rule{}},
+			err:      true,
+		},
+		{
+			name:     "should return an error if the request is anonymous",
+			bindings: []*grappapb.FieldBinding{{Field: "full_method", Claim: "sub"}},
+			req:      &grappapb.MethodRule{FullMethod: "subject"},
+			err:      true,
+		},
+		{
+			name:     "should return an error if the claim does not exist",
+			bindings: []*grappapb.FieldBinding{{Field: "full_method", Claim: "invalid"}},
+			claims:   claims,
+			req:      &grappapb.MethodRule{FullMethod: "subject"},
+			err:      true,
+		},
+		{
+			name:     "should return an error if the field is not set",
+			bindings: []*grappapb.FieldBinding{{Field: "full_method", Claim: "sub"}},
+			claims:   claims,
+			req:      &grappapb.MethodRule{},
+			err:      true,
+		},
+		{
+			name:     "should return an error if the field does not equal the claim",
+			bindings: []*grappapb.FieldBinding{{Field: "full_method", Claim: "sub"}},
+			claims:   claims,
+			req:      &grappapb.MethodRule{FullMethod: "other"},
+			err:      true,
+		},
+		{
+			name:     "should return an error if the equals claim is an array",
+			bindings: []*grappapb.FieldBinding{{Field: "full_method", Claim: "groups"}},
+			claims:   claims,
+			req:      &grappapb.MethodRule{FullMethod: "a"},
+			err:      true,
+		},
+		{
+			name: "should return an error if any binding is invalid",
+			bindings: []*grappapb.FieldBinding{
+				{Field: "full_method", Claim: "sub"},
+				{Field: "rule.policy", Claim: "realm.id"},
+			},
+			claims: claims,
+			req:    &grappapb.MethodRule{FullMethod: "subject", Rule: &grappapb.Rule{Policy: "other"}},
+			err:    true,
+		},
+		{
+			name:     "should return an error if a repeated field value is not in the claim",
+			bindings: []*grappapb.FieldBinding{{Field: "rule.require_scope", Claim: "groups", Operator: grappapb.FieldBinding_IN}},
+			claims:   claims,
+			req:      &grappapb.MethodRule{Rule: &grappapb.Rule{RequireScope: []string{"a", "c"}}},
+			err:      true,
+		},
+		{
+			name:     "should return an error if a repeated field is empty",
+			bindings: []*grappapb.FieldBinding{{Field: "rule.require_scope", Claim: "groups", Operator: grappapb.FieldBinding_IN}},
+			claims:   claims,
+			req:      &grappapb.MethodRule{Rule: &grappapb.Rule{}},
+			err:      true,
+		},
+		{
+			name:     "should allow fields that equal the claim",
+			bindings: []*grappapb.FieldBinding{{Field: "full_method", Claim: "sub"}},
+			claims:   claims,
+			req:      &grappapb.MethodRule{FullMethod: "subject"},
+		},
+		{
+			name:     "should allow nested fields that equal nested claims",
+			bindings: []*grappapb.FieldBinding{{Field: "rule.policy", Claim: "realm.id"}},
+			claims:   claims,
+			req:      &grappapb.MethodRule{Rule: &grappapb.Rule{Policy: "realm"}},
+		},
+		{
+			name:     "should allow integer fields that equal the claim",
+			bindings: []*grappapb.FieldBinding{{Field: "value", Claim: "tenant_id"}},
+			claims:   claims,
+			req:      wrapperspb.Int64(123),
+		},
+		{
+			name:     "should allow fields in the claim",
+			bindings: []*grappapb.FieldBinding{{Field: "full_method", Claim: "groups", Operator: grappapb.FieldBinding_IN}},
+			claims:   claims,
+			req:      &grappapb.MethodRule{FullMethod: "b"},
+		},
+		{
+			name:     "should allow repeated fields in the claim",
+			bindings: []*grappapb.FieldBinding{{Field: "rule.require_scope", Claim: "groups", Operator: grappapb.FieldBinding_IN}},
+			claims:   claims,
+			req:      &grappapb.MethodRule{Rule: &grappapb.Rule{RequireScope: []string{"b", "a"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := grappa.New(grappa.HMAC([]byte("secretkey")))
+			sut.Register("/package.Service/Method", &grappapb.Rule{
+				AllowAnonymous: tt.claims == nil,
+				BindField:      tt.bindings,
+			})
+
+			info := &grpc.UnaryServerInfo{FullMethod: "/package.Service/Method"}
+			_, err := sut.UnaryInterceptor(newTokenContext(tt.claims), tt.req, info, func(context.Context, interface{}) (interface{}, error) {
+				return nil, nil
+			})
+
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
+
+func TestFieldBinding_StreamInterceptor(t *testing.T) {
+	tests := []struct {
+		name string
+		msgs []string
+		exp  []string
+		err  bool
+	}{
+		{
+			name: "should return an error if a message field is invalid",
+			msgs: []string{"subject", "other", "subject"},
+			exp:  []string{"subject"},
+			err:  true,
+		},
+		{
+			name: "should verify each message",
+			msgs: []string{"subject", "subject"},
+			exp:  []string{"subject", "subject"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := grappa.New(grappa.HMAC([]byte("secretkey")))
+			sut.Register("/package.Service/Method", &grappapb.Rule{
+				BindField: []*grappapb.FieldBinding{{Field: "full_method", Claim: "sub"}},
+			})
+
+			ss := &serverStream{ctx: newTokenContext(jwt.MapClaims{"sub": "subject"})}
+			for _, m := range tt.msgs {
+				ss.msgs = append(ss.msgs, &grappapb.MethodRule{FullMethod: m})
+			}
+
+			info := &grpc.StreamServerInfo{FullMethod: "/package.Service/Method"}
+
+			var act []string
+			err := sut.StreamInterceptor(nil, ss, info, func(_ interface{}, ss grpc.ServerStream) error {
+				for {
+					m := new(grappapb.MethodRule)
+					if err := ss.RecvMsg(m); err != nil {
+						if err == io.EOF {
+							return nil
+						}
+						return err
+					}
+					act = append(act, m.GetFullMethod())
+				}
+			})
+
+			assertErrorExists(t, err, tt.err)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestFieldBinding_Register(t *testing.T) {
+	t.Run("should return an error if the binding is invalid", func(t *testing.T) {
+		sut := grappa.New(grappa.HMAC([]byte("secretkey")))
+
+		err := sut.Load(map[string]*grappapb.Rule{
+			"/package.Service/Method": {BindField: []*grappapb.FieldBinding{{Field: "full_method"}}},
+		})
+
+		assertErrorExists(t, err, true)
+	})
+}
@@ -2,13 +2,30 @@ package grappa
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/golang-jwt/jwt"
+
+	"github.com/stevecallear/grappa/internal/convert"
+	"github.com/stevecallear/grappa/internal/scope"
+	"github.com/stevecallear/grappa/proto/grappapb"
 )
 
-// VerifyFunc represents a claims verification func
-type VerifyFunc func(Context, jwt.MapClaims) error
+type (
+	// VerifyFunc represents a claims verification func
+	VerifyFunc func(Context, jwt.MapClaims) error
+
+	// CustomVerifyFunc represents a custom claims verification func
+	CustomVerifyFunc func(Context, jwt.Claims) error
+
+	// verified records the rule requirements that have been verified, so that
+	// rules are denied if the verifier for a requirement is not configured
+	verified struct {
+		roles  bool
+		claims bool
+	}
+)
 
 // VerifyIssuer verifies the issuer claim
 func VerifyIssuer(iss string) VerifyFunc {
@@ -41,32 +58,251 @@ func VerifyAudience(aud []string) VerifyFunc {
 }
 
 // VerifyScope verifies the scope claim
-func VerifyScope() VerifyFunc {
+// at least one of the required scopes must be present, and the scope
+// expression must be satisfied if specified. The scope claim defaults to
+// "scope", but alternate claim names can be specified, e.g. grappa.VerifyScope("scope", "scp")
+// each claim can be either a space delimited string or a string array
+// rules that do not require a scope, but that specify other requirements, are not verified
+func VerifyScope(claims ...string) VerifyFunc {
+	if len(claims) < 1 {
+		claims = []string{"scope"}
+	}
+
 	return func(ctx Context, c jwt.MapClaims) error {
-		s, ok := getClaimStr(c, "scope")
-		if !ok {
+		if !requiresScope(ctx.Rule) && hasRequirements(ctx.Rule) {
+			return nil
+		}
+
+		var ss []string
+		var found bool
+		for _, cn := range claims {
+			if cs, ok := getClaimStrs(c, cn); ok {
+				ss = append(ss, cs...)
+				found = true
+			}
+		}
+
+		if !found {
 			return errors.New("invalid scope claim")
 		}
 
-		ss := strings.Fields(s)
-		for _, rs := range ctx.Rule.GetRequireScope() {
+		has := func(rs string) bool {
 			for _, cs := range ss {
 				if strings.EqualFold(rs, cs) {
-					return nil
+					return true
+				}
+			}
+			return false
+		}
+
+		rss, expr := ctx.Rule.GetRequireScope(), ctx.Rule.GetScopeExpression()
+		if len(rss) > 0 || expr == "" {
+			if !hasAny(rss, has) {
+				return errors.New("invalid scope claim")
+			}
+		}
+
+		if expr != "" {
+			// registered rules are pre-compiled, but the context may be created by the caller
+			e := ctx.scope
+			if e == nil {
+				var err error
+				if e, err = scope.Parse(expr); err != nil {
+					return err
+				}
+			}
+
+			if !e.Eval(has) {
+				return errors.New("invalid scope claim")
+			}
+		}
+
+		return nil
+	}
+}
+
+// check returns an error if the rule requirements have not been verified
+func (v *verified) check(r *grappapb.Rule) error {
+	if len(r.GetRequireRole()) > 0 && !v.roles {
+		return errors.New("roles not verified")
+	}
+
+	if len(r.GetRequireClaim()) > 0 && !v.claims {
+		return errors.New("claims not verified")
+	}
+
+	return nil
+}
+
+func requiresScope(r *grappapb.Rule) bool {
+	return len(r.GetRequireScope()) > 0 || r.GetScopeExpression() != ""
+}
+
+// hasRequirements returns true if the rule specifies requirements other than scopes
+// each is either verified by the authorizor, or denied if its verifier is not configured
+func hasRequirements(r *grappapb.Rule) bool {
+	return len(r.GetRequireClaim()) > 0 ||
+		len(r.GetRequireRole()) > 0 ||
+		r.GetCondition() != "" ||
+		r.GetPolicy() != "" ||
+		len(r.GetBindField()) > 0
+}
+
+func hasAny(vs []string, has func(string) bool) bool {
+	for _, v := range vs {
+		if has(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// VerifyRoles verifies that the roles claim at the specified path contains at
+// least one of the roles required by the rule, e.g. grappa.VerifyRoles("realm_access.roles")
+// the claim can be either a string array or a space delimited string
+// rules that require roles are denied if this verifier is not configured
+func VerifyRoles(claimPath string) VerifyFunc {
+	return func(ctx Context, c jwt.MapClaims) error {
+		rrs := ctx.Rule.GetRequireRole()
+		if len(rrs) < 1 {
+			return nil
+		}
+
+		crs, ok := getClaimStrs(c, claimPath)
+		if !ok {
+			return errors.New("invalid roles claim")
+		}
+
+		has := func(rr string) bool {
+			for _, cr := range crs {
+				if rr == cr {
+					return true
 				}
 			}
+			return false
+		}
+
+		if !hasAny(rrs, has) {
+			return errors.New("invalid roles claim")
 		}
 
-		return errors.New("invalid scope claim")
+		if ctx.verified != nil {
+			ctx.verified.roles = true
+		}
+
+		return nil
 	}
 }
 
-func getClaimStr(c jwt.MapClaims, claim string) (string, bool) {
-	if v, ok := c[claim]; ok {
-		if s, ok := v.(string); ok {
-			return s, true
+// VerifyRequiredClaims verifies the claim requirements specified by the rule
+// if a claim is an array, then a requirement is satisfied if any value satisfies it
+// rules that require claims are denied if this verifier is not configured
+func VerifyRequiredClaims() VerifyFunc {
+	return func(ctx Context, c jwt.MapClaims) error {
+		for _, cr := range ctx.Rule.GetRequireClaim() {
+			if !verifyClaimRequirement(c, cr) {
+				return fmt.Errorf("invalid %s claim", cr.GetClaim())
+			}
+		}
+
+		if ctx.verified != nil {
+			ctx.verified.claims = true
 		}
+
+		return nil
+	}
+}
+
+func verifyClaimRequirement(c jwt.MapClaims, cr *grappapb.ClaimRequirement) bool {
+	v, ok := getClaim(c, cr.GetClaim())
+	if !ok {
+		return false
 	}
 
-	return "", false
+	var match func(string) bool
+	switch cr.GetOperator() {
+	case grappapb.ClaimRequirement_EXISTS:
+		return true
+	case grappapb.ClaimRequirement_EQUALS, grappapb.ClaimRequirement_IN:
+		match = func(s string) bool {
+			for _, rv := range cr.GetValues() {
+				if s == rv {
+					return true
+				}
+			}
+			return false
+		}
+	case grappapb.ClaimRequirement_PREFIX:
+		match = func(s string) bool {
+			for _, rv := range cr.GetValues() {
+				if strings.HasPrefix(s, rv) {
+					return true
+				}
+			}
+			return false
+		}
+	default:
+		return false
+	}
+
+	if vs, ok := v.([]interface{}); ok {
+		for _, ev := range vs {
+			if match(convert.ToString(ev)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return match(convert.ToString(v))
+}
+
+// getClaim returns the value of the claim at the specified path
+// nested claims are resolved using a dot separated path, e.g. realm_access.roles
+func getClaim(c jwt.MapClaims, path string) (interface{}, bool) {
+	if v, ok := c[path]; ok {
+		return v, true
+	}
+
+	var v interface{} = map[string]interface{}(c)
+	for _, k := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		if v, ok = m[k]; !ok {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// getClaimStrs returns the values of a string array or space delimited string claim
+func getClaimStrs(c jwt.MapClaims, path string) ([]string, bool) {
+	v, ok := getClaim(c, path)
+	if !ok {
+		return nil, false
+	}
+
+	switch tv := v.(type) {
+	case string:
+		return strings.Fields(tv), true
+	case []string:
+		return tv, true
+	case []interface{}:
+		ss := make([]string, 0, len(tv))
+		for _, ev := range tv {
+			s, ok := ev.(string)
+			if !ok {
+				return nil, false
+			}
+			ss = append(ss, s)
+		}
+		return ss, true
+	default:
+		return nil, false
+	}
 }
@@ -109,3 +109,298 @@ func TestVerifyScope(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyScope_ClaimNames(t *testing.T) {
+	var rule = &grappapb.Rule{
+		RequireScope: []string{"scope_a"},
+	}
+
+	tests := []struct {
+		name   string
+		claims []string
+		input  jwt.MapClaims
+		err    bool
+	}{
+		{
+			name:   "should return an error if none of the claims are present",
+			claims: []string{"scope", "scp"},
+			input:  jwt.MapClaims{"scopes": "scope_a"},
+			err:    true,
+		},
+		{
+			name:   "should return an error if the claim is invalid",
+			claims: []string{"scope"},
+			input:  jwt.MapClaims{"scope": 1},
+			err:    true,
+		},
+		{
+			name:  "should accept array scope claims by default",
+			input: jwt.MapClaims{"scope": []interface{}{"scope_b", "SCOPE_A"}},
+		},
+		{
+			name:   "should use the specified claim names",
+			claims: []string{"scope", "scp"},
+			input:  jwt.MapClaims{"scp": "scope_a scope_b"},
+		},
+		{
+			name:   "should combine the scopes of each claim",
+			claims: []string{"scope", "scp"},
+			input:  jwt.MapClaims{"scope": "scope_b", "scp": []interface{}{"scope_a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := grappa.VerifyScope(tt.claims...)(grappa.Context{
+				Rule: rule,
+			}, tt.input)
+
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
+
+func TestVerifyScope_ScopeExpression(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  *grappapb.Rule
+		input jwt.MapClaims
+		err   bool
+	}{
+		{
+			name: "should return an error if the expression is invalid",
+			rule: &grappapb.Rule{
+				ScopeExpression: "admin ||",
+			},
+			input: jwt.MapClaims{"scope": "admin"},
+			err:   true,
+		},
+		{
+			name: "should return an error if the expression is not satisfied",
+			rule: &grappapb.Rule{
+				ScopeExpression: "admin || (orders:read && orders:write)",
+			},
+			input: jwt.MapClaims{"scope": "orders:read"},
+			err:   true,
+		},
+		{
+			name: "should return an error if the required scopes are not satisfied",
+			rule: &grappapb.Rule{
+				RequireScope:    []string{"user"},
+				ScopeExpression: "orders:read",
+			},
+			input: jwt.MapClaims{"scope": "orders:read"},
+			err:   true,
+		},
+		{
+			name: "should return nil if the expression is satisfied",
+			rule: &grappapb.Rule{
+				ScopeExpression: "admin || (orders:read && orders:write)",
+			},
+			input: jwt.MapClaims{"scope": "ORDERS:READ orders:write"},
+		},
+		{
+			name: "should return nil if the required scopes and expression are satisfied",
+			rule: &grappapb.Rule{
+				RequireScope:    []string{"user"},
+				ScopeExpression: "orders:read && !guest",
+			},
+			input: jwt.MapClaims{"scope": "user orders:read"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := grappa.VerifyScope()(grappa.Context{
+				Rule: tt.rule,
+			}, tt.input)
+
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
+
+func TestVerifyRoles(t *testing.T) {
+	var rule = &grappapb.Rule{
+		RequireRole: []string{"admin", "support"},
+	}
+
+	tests := []struct {
+		name  string
+		rule  *grappapb.Rule
+		path  string
+		input jwt.MapClaims
+		err   bool
+	}{
+		{
+			name:  "should return nil if the rule does not require roles",
+			rule:  &grappapb.Rule{},
+			path:  "roles",
+			input: jwt.MapClaims{},
+		},
+		{
+			name:  "should return an error if the roles claim is missing",
+			rule:  rule,
+			path:  "roles",
+			input: jwt.MapClaims{},
+			err:   true,
+		},
+		{
+			name:  "should return an error if the roles claim is invalid",
+			rule:  rule,
+			path:  "roles",
+			input: jwt.MapClaims{"roles": []interface{}{"admin", 1}},
+			err:   true,
+		},
+		{
+			name:  "should return an error if none of the required roles are present",
+			rule:  rule,
+			path:  "roles",
+			input: jwt.MapClaims{"roles": []interface{}{"user"}},
+			err:   true,
+		},
+		{
+			name:  "should return nil if a required role is present in an array claim",
+			rule:  rule,
+			path:  "groups",
+			input: jwt.MapClaims{"groups": []interface{}{"user", "support"}},
+		},
+		{
+			name:  "should return nil if a required role is present in a space delimited claim",
+			rule:  rule,
+			path:  "roles",
+			input: jwt.MapClaims{"roles": "user admin"},
+		},
+		{
+			name:  "should return nil if a required role is present in a nested claim",
+			rule:  rule,
+			path:  "realm_access.roles",
+			input: jwt.MapClaims{"realm_access": map[string]interface{}{"roles": []interface{}{"admin"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := grappa.VerifyRoles(tt.path)(grappa.Context{
+				Rule: tt.rule,
+			}, tt.input)
+
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
+
+func TestVerifyRequiredClaims(t *testing.T) {
+	tests := []struct {
+		name  string
+		reqs  []*grappapb.ClaimRequirement
+		input jwt.MapClaims
+		err   bool
+	}{
+		{
+			name:  "should return nil if there are no requirements",
+			input: jwt.MapClaims{},
+		},
+		{
+			name: "should return an error if the claim is missing",
+			reqs: []*grappapb.ClaimRequirement{
+				{Claim: "email_verified", Operator: grappapb.ClaimRequirement_EXISTS},
+			},
+			input: jwt.MapClaims{},
+			err:   true,
+		},
+		{
+			name: "should return nil if the claim exists",
+			reqs: []*grappapb.ClaimRequirement{
+				{Claim: "email_verified", Operator: grappapb.ClaimRequirement_EXISTS},
+			},
+			input: jwt.MapClaims{"email_verified": false},
+		},
+		{
+			name: "should return an error if the claim is not equal",
+			reqs: []*grappapb.ClaimRequirement{
+				{Claim: "tenant_tier", Values: []string{"premium"}},
+			},
+			input: jwt.MapClaims{"tenant_tier": "basic"},
+			err:   true,
+		},
+		{
+			name: "should compare the string value of non-string claims",
+			reqs: []*grappapb.ClaimRequirement{
+				{Claim: "email_verified", Values: []string{"true"}},
+			},
+			input: jwt.MapClaims{"email_verified": true},
+		},
+		{
+			name: "should return an error if the claim is not in the values",
+			reqs: []*grappapb.ClaimRequirement{
+				{Claim: "tenant_tier", Operator: grappapb.ClaimRequirement_IN, Values: []string{"premium", "gold"}},
+			},
+			input: jwt.MapClaims{"tenant_tier": "basic"},
+			err:   true,
+		},
+		{
+			name: "should return nil if any array value is in the values",
+			reqs: []*grappapb.ClaimRequirement{
+				{Claim: "groups", Operator: grappapb.ClaimRequirement_IN, Values: []string{"admin", "ops"}},
+			},
+			input: jwt.MapClaims{"groups": []interface{}{"user", "ops"}},
+		},
+		{
+			name: "should return an error if the claim does not have the prefix",
+			reqs: []*grappapb.ClaimRequirement{
+				{Claim: "sub", Operator: grappapb.ClaimRequirement_PREFIX, Values: []string{"service|"}},
+			},
+			input: jwt.MapClaims{"sub": "user|123"},
+			err:   true,
+		},
+		{
+			name: "should return nil if the claim has the prefix",
+			reqs: []*grappapb.ClaimRequirement{
+				{Claim: "sub", Operator: grappapb.ClaimRequirement_PREFIX, Values: []string{"service|"}},
+			},
+			input: jwt.MapClaims{"sub": "service|123"},
+		},
+		{
+			name: "should resolve nested claim paths",
+			reqs: []*grappapb.ClaimRequirement{
+				{Claim: "realm_access.roles", Operator: grappapb.ClaimRequirement_IN, Values: []string{"admin"}},
+			},
+			input: jwt.MapClaims{"realm_access": map[string]interface{}{"roles": []interface{}{"admin"}}},
+		},
+		{
+			name: "should return an error if a nested claim path is invalid",
+			reqs: []*grappapb.ClaimRequirement{
+				{Claim: "realm_access.roles", Operator: grappapb.ClaimRequirement_EXISTS},
+			},
+			input: jwt.MapClaims{"realm_access": "admin"},
+			err:   true,
+		},
+		{
+			name: "should prefer claims with dotted names",
+			reqs: []*grappapb.ClaimRequirement{
+				{Claim: "https://example.com/tier", Values: []string{"premium"}},
+			},
+			input: jwt.MapClaims{"https://example.com/tier": "premium"},
+		},
+		{
+			name: "should return an error if any requirement is not satisfied",
+			reqs: []*grappapb.ClaimRequirement{
+				{Claim: "tenant_tier", Values: []string{"premium"}},
+				{Claim: "email_verified", Values: []string{"true"}},
+			},
+			input: jwt.MapClaims{"tenant_tier": "premium", "email_verified": false},
+			err:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := grappa.VerifyRequiredClaims()(grappa.Context{
+				Rule: &grappapb.Rule{RequireClaim: tt.reqs},
+			}, tt.input)
+
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
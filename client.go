@@ -0,0 +1,179 @@
+package grappa
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+type (
+	// Token represents an access token
+	Token struct {
+		AccessToken string
+		Expiry      time.Time
+	}
+
+	// TokenSource represents an access token source
+	TokenSource interface {
+		Token(ctx context.Context, fullMethod string) (Token, error)
+	}
+
+	// TokenSourceFunc represents an access token source func
+	TokenSourceFunc func(ctx context.Context, fullMethod string) (Token, error)
+
+	cachedTokenSource struct {
+		src     TokenSource
+		window  time.Duration
+		mu      sync.Mutex
+		tokens  map[string]Token
+		fetches map[string]*tokenFetch
+	}
+
+	tokenFetch struct {
+		done  chan struct{}
+		token Token
+		err   error
+	}
+
+	perRPCCredentials struct {
+		src        TokenSource
+		requireTLS bool
+	}
+)
+
+// Token returns a token for the specified method
+func (fn TokenSourceFunc) Token(ctx context.Context, fullMethod string) (Token, error) {
+	return fn(ctx, fullMethod)
+}
+
+// StaticTokenSource returns a token source that always returns the specified token
+func StaticTokenSource(accessToken string) TokenSource {
+	return TokenSourceFunc(func(context.Context, string) (Token, error) {
+		return Token{AccessToken: accessToken}, nil
+	})
+}
+
+// CachedTokenSource returns a token source that caches the tokens returned by src
+// by method, and refreshes them once the current time is within the window before expiry.
+// Tokens without an expiry are cached indefinitely. Concurrent requests for the same
+// method wait for a single fetch, and fetches for different methods do not block each other.
+func CachedTokenSource(src TokenSource, window time.Duration) TokenSource {
+	return &cachedTokenSource{
+		src:     src,
+		window:  window,
+		tokens:  map[string]Token{},
+		fetches: map[string]*tokenFetch{},
+	}
+}
+
+// UnaryClientInterceptor returns a unary client interceptor that attaches a
+// bearer token from the source to each request
+func UnaryClientInterceptor(src TokenSource) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, err := withToken(ctx, src, method)
+		if err != nil {
+			return err
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a stream client interceptor that attaches a
+// bearer token from the source to each stream
+func StreamClientInterceptor(src TokenSource) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, err := withToken(ctx, src, method)
+		if err != nil {
+			return nil, err
+		}
+
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// PerRPCCredentials returns per rpc credentials that attach a bearer token from the source
+// to each request. If requireTLS is true, then the credentials will only be sent over
+// a secure connection.
+func PerRPCCredentials(src TokenSource, requireTLS bool) credentials.PerRPCCredentials {
+	return &perRPCCredentials{
+		src:        src,
+		requireTLS: requireTLS,
+	}
+}
+
+func (s *cachedTokenSource) Token(ctx context.Context, fullMethod string) (Token, error) {
+	s.mu.Lock()
+
+	if t, ok := s.tokens[fullMethod]; ok && t.AccessToken != "" && (t.Expiry.IsZero() || time.Now().Add(s.window).Before(t.Expiry)) {
+		s.mu.Unlock()
+		return t, nil
+	}
+
+	if f, ok := s.fetches[fullMethod]; ok {
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Token{}, ctx.Err()
+		case <-f.done:
+		}
+
+		// the fetch was cancelled by the caller that started it, rather than failing
+		if errors.Is(f.err, context.Canceled) || errors.Is(f.err, context.DeadlineExceeded) {
+			return s.Token(ctx, fullMethod)
+		}
+
+		return f.token, f.err
+	}
+
+	f := &tokenFetch{done: make(chan struct{})}
+	s.fetches[fullMethod] = f
+	s.mu.Unlock()
+
+	f.token, f.err = s.src.Token(ctx, fullMethod)
+	if f.err != nil {
+		f.token = Token{}
+	}
+
+	s.mu.Lock()
+	delete(s.fetches, fullMethod)
+	if f.err == nil {
+		s.tokens[fullMethod] = f.token
+	}
+	s.mu.Unlock()
+
+	close(f.done)
+	return f.token, f.err
+}
+
+func (c *perRPCCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	ri, _ := credentials.RequestInfoFromContext(ctx)
+
+	t, err := c.src.Token(ctx, ri.Method)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"authorization": "Bearer " + t.AccessToken,
+	}, nil
+}
+
+func (c *perRPCCredentials) RequireTransportSecurity() bool {
+	return c.requireTLS
+}
+
+func withToken(ctx context.Context, src TokenSource, method string) (context.Context, error) {
+	t, err := src.Token(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+t.AccessToken), nil
+}
@@ -0,0 +1,323 @@
+package grappa_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/stevecallear/grappa"
+	"github.com/stevecallear/grappa/proto/grappapb"
+)
+
+func TestCachedTokenSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		expiry time.Duration
+		window time.Duration
+		exp    int
+	}{
+		{
+			name: "should cache tokens without an expiry",
+			exp:  1,
+		},
+		{
+			name:   "should cache tokens until the refresh window",
+			expiry: time.Hour,
+			window: time.Minute,
+			exp:    1,
+		},
+		{
+			name:   "should refresh tokens within the refresh window",
+			expiry: time.Minute,
+			window: time.Hour,
+			exp:    3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var n int
+			sut := grappa.CachedTokenSource(grappa.TokenSourceFunc(func(context.Context, string) (grappa.Token, error) {
+				n++
+
+				tok := grappa.Token{AccessToken: "token"}
+				if tt.expiry > 0 {
+					tok.Expiry = time.Now().Add(tt.expiry)
+				}
+				return tok, nil
+			}), tt.window)
+
+			for i := 0; i < 3; i++ {
+				act, err := sut.Token(context.Background(), "/package.Service/Method")
+				assertErrorExists(t, err, false)
+
+				if act.AccessToken != "token" {
+					t.Errorf("got %s, expected token", act.AccessToken)
+				}
+			}
+
+			if n != tt.exp {
+				t.Errorf("got %d, expected %d", n, tt.exp)
+			}
+		})
+	}
+}
+
+func TestCachedTokenSource_Methods(t *testing.T) {
+	t.Run("should cache tokens by method", func(t *testing.T) {
+		var n int
+		sut := grappa.CachedTokenSource(grappa.TokenSourceFunc(func(_ context.Context, fullMethod string) (grappa.Token, error) {
+			n++
+			return grappa.Token{AccessToken: fullMethod}, nil
+		}), time.Minute)
+
+		for _, m := range []string{"/package.Service/MethodA", "/package.Service/MethodB", "/package.Service/MethodA"} {
+			act, err := sut.Token(context.Background(), m)
+			assertErrorExists(t, err, false)
+			assertDeepEqual(t, act.AccessToken, m)
+		}
+
+		assertDeepEqual(t, n, 2)
+	})
+}
+
+func TestCachedTokenSource_Concurrency(t *testing.T) {
+	t.Run("should not block token requests for other methods", func(t *testing.T) {
+		fetching, release := make(chan struct{}), make(chan struct{})
+		sut := grappa.CachedTokenSource(grappa.TokenSourceFunc(func(_ context.Context, fullMethod string) (grappa.Token, error) {
+			if fullMethod == "/package.Service/Slow" {
+				close(fetching)
+				<-release
+			}
+			return grappa.Token{AccessToken: fullMethod}, nil
+		}), time.Minute)
+
+		slow := make(chan error)
+		go func() {
+			_, err := sut.Token(context.Background(), "/package.Service/Slow")
+			slow <- err
+		}()
+		<-fetching
+
+		fast := make(chan error)
+		go func() {
+			_, err := sut.Token(context.Background(), "/package.Service/Fast")
+			fast <- err
+		}()
+
+		select {
+		case err := <-fast:
+			assertErrorExists(t, err, false)
+		case <-time.After(time.Second):
+			t.Error("token request blocked by a fetch for another method")
+		}
+
+		close(release)
+		assertErrorExists(t, <-slow, false)
+	})
+
+	t.Run("should return context errors while waiting for a fetch", func(t *testing.T) {
+		fetching, release := make(chan struct{}), make(chan struct{})
+		sut := grappa.CachedTokenSource(grappa.TokenSourceFunc(func(context.Context, string) (grappa.Token, error) {
+			close(fetching)
+			<-release
+			return grappa.Token{AccessToken: "token"}, nil
+		}), time.Minute)
+
+		first := make(chan error)
+		go func() {
+			_, err := sut.Token(context.Background(), "/package.Service/Method")
+			first <- err
+		}()
+		<-fetching
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		waiting := make(chan error, 1)
+		go func() {
+			_, err := sut.Token(ctx, "/package.Service/Method")
+			waiting <- err
+		}()
+
+		select {
+		case err := <-waiting:
+			assertDeepEqual(t, err, context.DeadlineExceeded)
+		case <-time.After(time.Second):
+			t.Error("token request did not return the context error")
+		}
+
+		close(release)
+		assertErrorExists(t, <-first, false)
+	})
+
+	t.Run("should fetch tokens for the same method once", func(t *testing.T) {
+		var n int32
+		release := make(chan struct{})
+		sut := grappa.CachedTokenSource(grappa.TokenSourceFunc(func(context.Context, string) (grappa.Token, error) {
+			atomic.AddInt32(&n, 1)
+			<-release
+			return grappa.Token{AccessToken: "token"}, nil
+		}), time.Minute)
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 5)
+		for i := 0; i < cap(errs); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := sut.Token(context.Background(), "/package.Service/Method")
+				errs <- err
+			}()
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			assertErrorExists(t, err, false)
+		}
+		assertDeepEqual(t, atomic.LoadInt32(&n), int32(1))
+	})
+}
+
+func TestCachedTokenSource_Error(t *testing.T) {
+	t.Run("should return source errors", func(t *testing.T) {
+		sut := grappa.CachedTokenSource(grappa.TokenSourceFunc(func(context.Context, string) (grappa.Token, error) {
+			return grappa.Token{}, errors.New("error")
+		}), time.Minute)
+
+		_, err := sut.Token(context.Background(), "/package.Service/Method")
+		assertErrorExists(t, err, true)
+	})
+}
+
+func TestClientInterceptors(t *testing.T) {
+	key := []byte("secretkey")
+	token := newHMAC(key, jwt.MapClaims{
+		"sub": "subject",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+
+	tests := []struct {
+		name   string
+		dialFn func(grappa.TokenSource) []grpc.DialOption
+		src    grappa.TokenSource
+		callFn func(grpc_health_v1.HealthClient) error
+		err    bool
+	}{
+		{
+			name: "should return an error if the token source fails",
+			dialFn: func(src grappa.TokenSource) []grpc.DialOption {
+				return []grpc.DialOption{grpc.WithUnaryInterceptor(grappa.UnaryClientInterceptor(src))}
+			},
+			src: grappa.TokenSourceFunc(func(context.Context, string) (grappa.Token, error) {
+				return grappa.Token{}, errors.New("error")
+			}),
+			callFn: check,
+			err:    true,
+		},
+		{
+			name: "should return an error if the token is invalid",
+			dialFn: func(src grappa.TokenSource) []grpc.DialOption {
+				return []grpc.DialOption{grpc.WithUnaryInterceptor(grappa.UnaryClientInterceptor(src))}
+			},
+			src:    grappa.StaticTokenSource("invalid"),
+			callFn: check,
+			err:    true,
+		},
+		{
+			name: "should attach tokens using the unary interceptor",
+			dialFn: func(src grappa.TokenSource) []grpc.DialOption {
+				return []grpc.DialOption{grpc.WithUnaryInterceptor(grappa.UnaryClientInterceptor(src))}
+			},
+			src:    grappa.StaticTokenSource(token),
+			callFn: check,
+		},
+		{
+			name: "should attach tokens using the stream interceptor",
+			dialFn: func(src grappa.TokenSource) []grpc.DialOption {
+				return []grpc.DialOption{grpc.WithStreamInterceptor(grappa.StreamClientInterceptor(src))}
+			},
+			src:    grappa.StaticTokenSource(token),
+			callFn: watch,
+		},
+		{
+			name: "should attach tokens using per rpc credentials",
+			dialFn: func(src grappa.TokenSource) []grpc.DialOption {
+				return []grpc.DialOption{grpc.WithPerRPCCredentials(grappa.PerRPCCredentials(src, false))}
+			},
+			src: grappa.TokenSourceFunc(func(_ context.Context, fullMethod string) (grappa.Token, error) {
+				if fullMethod == "" {
+					return grappa.Token{}, errors.New("method not set")
+				}
+				return grappa.Token{AccessToken: token}, nil
+			}),
+			callFn: check,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := grappa.New(grappa.HMAC(key))
+			auth.Register("/grpc.health.v1.Health/*", &grappapb.Rule{})
+
+			cli := newHealthClient(t, auth, tt.dialFn(tt.src)...)
+
+			err := tt.callFn(cli)
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
+
+func newHealthClient(t *testing.T, auth *grappa.Authorizor, opts ...grpc.DialOption) grpc_health_v1.HealthClient {
+	lis := bufconn.Listen(1024 * 1024)
+
+	svr := grpc.NewServer(
+		grpc.UnaryInterceptor(auth.UnaryInterceptor),
+		grpc.StreamInterceptor(auth.StreamInterceptor))
+
+	grpc_health_v1.RegisterHealthServer(svr, health.NewServer())
+
+	go svr.Serve(lis)
+	t.Cleanup(svr.Stop)
+
+	opts = append(opts, grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+
+	conn, err := grpc.Dial("bufnet", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+func check(cli grpc_health_v1.HealthClient) error {
+	_, err := cli.Check(context.Background(), new(grpc_health_v1.HealthCheckRequest))
+	return err
+}
+
+func watch(cli grpc_health_v1.HealthClient) error {
+	s, err := cli.Watch(context.Background(), new(grpc_health_v1.HealthCheckRequest))
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Recv()
+	return err
+}
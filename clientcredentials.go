@@ -0,0 +1,248 @@
+package grappa
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stevecallear/grappa/internal/scope"
+	"github.com/stevecallear/grappa/proto/grappapb"
+)
+
+type (
+	// ClientCredentials represents an oauth2 client credentials token source
+	ClientCredentials struct {
+		tokenURL     string
+		clientID     string
+		clientSecret string
+		opts         ClientCredentialsOptions
+		mu           sync.Mutex
+		router       *router
+		tokens       map[string]Token
+		fetches      map[string]*tokenFetch
+	}
+
+	// ClientCredentialsOptions represents a set of client credentials options
+	ClientCredentialsOptions struct {
+		Client         *http.Client
+		Scopes         []string
+		EndpointParams url.Values
+		Window         time.Duration
+		Retries        int
+		Backoff        time.Duration
+	}
+
+	tokenResponse struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+		Error       string `json:"error"`
+	}
+
+	retryableError struct {
+		error
+	}
+)
+
+var defaultClientCredentialsOptions = ClientCredentialsOptions{
+	Client:  http.DefaultClient,
+	Window:  time.Minute,
+	Retries: 2,
+	Backoff: 100 * time.Millisecond,
+}
+
+// NewClientCredentials returns a new client credentials token source for the specified token endpoint
+func NewClientCredentials(tokenURL, clientID, clientSecret string, optFns ...func(*ClientCredentialsOptions)) *ClientCredentials {
+	o := defaultClientCredentialsOptions
+	for _, fn := range optFns {
+		fn(&o)
+	}
+
+	return &ClientCredentials{
+		tokenURL:     tokenURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		opts:         o,
+		router:       newRouter(),
+		tokens:       map[string]Token{},
+		fetches:      map[string]*tokenFetch{},
+	}
+}
+
+// Register registers the rule for the specified method pattern
+// tokens for matching methods are requested with the scopes required by the rule,
+// including any scopes that are not negated by the scope expression
+// Register panics if the scope expression is invalid or a different rule is
+// already registered for the pattern
+func (c *ClientCredentials) Register(pattern string, r *grappapb.Rule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	nr := &rule{pattern: pattern, rule: r}
+	if e := r.GetScopeExpression(); e != "" {
+		var err error
+		if nr.scope, err = scope.Parse(e); err != nil {
+			panic(fmt.Errorf("invalid scope expression for pattern %s: %v", pattern, err))
+		}
+	}
+
+	if err := c.router.add(nr); err != nil {
+		panic(err)
+	}
+}
+
+// Token returns a token for the specified method
+// tokens are cached by scope until the refresh window before expiry
+// concurrent requests for the same scopes wait for a single fetch, and
+// fetches for different scopes do not block each other
+func (c *ClientCredentials) Token(ctx context.Context, fullMethod string) (Token, error) {
+	c.mu.Lock()
+
+	scopes := c.scopes(fullMethod)
+	key := strings.Join(scopes, " ")
+
+	if t, ok := c.tokens[key]; ok && (t.Expiry.IsZero() || time.Now().Add(c.opts.Window).Before(t.Expiry)) {
+		c.mu.Unlock()
+		return t, nil
+	}
+
+	if f, ok := c.fetches[key]; ok {
+		c.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Token{}, ctx.Err()
+		case <-f.done:
+		}
+
+		// the fetch was cancelled by the caller that started it, rather than failing
+		if errors.Is(f.err, context.Canceled) || errors.Is(f.err, context.DeadlineExceeded) {
+			return c.Token(ctx, fullMethod)
+		}
+
+		return f.token, f.err
+	}
+
+	f := &tokenFetch{done: make(chan struct{})}
+	c.fetches[key] = f
+	c.mu.Unlock()
+
+	f.token, f.err = c.fetchWithRetry(ctx, scopes)
+
+	c.mu.Lock()
+	delete(c.fetches, key)
+	if f.err == nil {
+		c.tokens[key] = f.token
+	}
+	c.mu.Unlock()
+
+	close(f.done)
+	return f.token, f.err
+}
+
+func (c *ClientCredentials) scopes(fullMethod string) []string {
+	ss := append([]string{}, c.opts.Scopes...)
+	if r, ok := c.router.match(fullMethod); ok {
+		ss = append(ss, r.rule.GetRequireScope()...)
+		if r.scope != nil {
+			ss = append(ss, scope.Scopes(r.scope)...)
+		}
+	}
+
+	sort.Strings(ss)
+
+	res := ss[:0]
+	for i, s := range ss {
+		if i == 0 || s != ss[i-1] {
+			res = append(res, s)
+		}
+	}
+
+	return res
+}
+
+func (c *ClientCredentials) fetchWithRetry(ctx context.Context, scopes []string) (Token, error) {
+	d := c.opts.Backoff
+	for i := 0; ; i++ {
+		t, err := c.fetch(ctx, scopes)
+		if err == nil {
+			return t, nil
+		}
+
+		if _, ok := err.(retryableError); !ok || i >= c.opts.Retries {
+			return Token{}, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return Token{}, ctx.Err()
+		case <-time.After(d):
+			d *= 2
+		}
+	}
+}
+
+func (c *ClientCredentials) fetch(ctx context.Context, scopes []string) (Token, error) {
+	v := url.Values{}
+	for k, vs := range c.opts.EndpointParams {
+		v[k] = vs
+	}
+
+	v.Set("grant_type", "client_credentials")
+	if len(scopes) > 0 {
+		v.Set("scope", strings.Join(scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, strings.NewReader(v.Encode()))
+	if err != nil {
+		return Token{}, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(c.clientID), url.QueryEscape(c.clientSecret))
+
+	res, err := c.opts.Client.Do(req)
+	if err != nil {
+		return Token{}, retryableError{err}
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return Token{}, retryableError{err}
+	}
+
+	var tr tokenResponse
+	jerr := json.Unmarshal(b, &tr)
+
+	if res.StatusCode != http.StatusOK {
+		err = fmt.Errorf("unexpected token status: %d %s", res.StatusCode, tr.Error)
+		if res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests {
+			return Token{}, retryableError{err}
+		}
+		return Token{}, err
+	}
+
+	if jerr != nil {
+		return Token{}, jerr
+	}
+
+	if tr.AccessToken == "" {
+		return Token{}, errors.New("invalid token response")
+	}
+
+	t := Token{AccessToken: tr.AccessToken}
+	if tr.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,282 @@
+package grappa_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/stevecallear/grappa"
+	"github.com/stevecallear/grappa/proto/grappapb"
+)
+
+type testIssuer struct {
+	key      []byte
+	mu       sync.Mutex
+	requests []string
+	statuses []int
+}
+
+func (i *testIssuer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.requests = append(i.requests, r.FormValue("scope"))
+
+	if len(i.statuses) > 0 {
+		s := i.statuses[0]
+		i.statuses = i.statuses[1:]
+		if s != http.StatusOK {
+			w.WriteHeader(s)
+			w.Write([]byte(`{"error":"invalid_request"}`))
+			return
+		}
+	}
+
+	if id, secret, ok := r.BasicAuth(); !ok || id != "client" || secret != "secret" || r.FormValue("grant_type") != "client_credentials" {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid_client"}`))
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": newHMAC(i.key, jwt.MapClaims{
+			"sub":   "client",
+			"scope": r.FormValue("scope"),
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		}),
+		"token_type": "Bearer",
+		"expires_in": 3600,
+	})
+}
+
+func TestClientCredentials_Token(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string
+		statuses []int
+		setup    func(*grappa.ClientCredentials)
+		methods  []string
+		exp      []string
+		err      bool
+	}{
+		{
+			name:    "should return an error if the credentials are invalid",
+			secret:  "invalid",
+			setup:   func(*grappa.ClientCredentials) {},
+			methods: []string{"/package.Service/Method"},
+			exp:     []string{"default"},
+			err:     true,
+		},
+		{
+			name:     "should not retry client errors",
+			secret:   "secret",
+			statuses: []int{http.StatusBadRequest},
+			setup:    func(*grappa.ClientCredentials) {},
+			methods:  []string{"/package.Service/Method"},
+			exp:      []string{"default"},
+			err:      true,
+		},
+		{
+			name:     "should return an error if the retries are exceeded",
+			secret:   "secret",
+			statuses: []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			setup:    func(*grappa.ClientCredentials) {},
+			methods:  []string{"/package.Service/Method"},
+			exp:      []string{"default", "default", "default"},
+			err:      true,
+		},
+		{
+			name:     "should retry server errors",
+			secret:   "secret",
+			statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			setup:    func(*grappa.ClientCredentials) {},
+			methods:  []string{"/package.Service/Method"},
+			exp:      []string{"default", "default", "default"},
+		},
+		{
+			name:    "should cache tokens",
+			secret:  "secret",
+			setup:   func(*grappa.ClientCredentials) {},
+			methods: []string{"/package.Service/MethodA", "/package.Service/MethodB"},
+			exp:     []string{"default"},
+		},
+		{
+			name:   "should request scopes for registered rules",
+			secret: "secret",
+			setup: func(c *grappa.ClientCredentials) {
+				c.Register("/package.Service/MethodA", &grappapb.Rule{RequireScope: []string{"scope_b", "scope_a"}})
+				c.Register("/package.Service/*", &grappapb.Rule{RequireScope: []string{"default"}})
+			},
+			methods: []string{"/package.Service/MethodA", "/package.Service/MethodB", "/package.Service/MethodA"},
+			exp:     []string{"default scope_a scope_b", "default"},
+		},
+		{
+			name:   "should request scopes for registered scope expressions",
+			secret: "secret",
+			setup: func(c *grappa.ClientCredentials) {
+				c.Register("/package.Service/Method", &grappapb.Rule{ScopeExpression: "scope_b && (scope_a || !scope_c)"})
+			},
+			methods: []string{"/package.Service/Method"},
+			exp:     []string{"default scope_a scope_b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iss := &testIssuer{key: []byte("secretkey"), statuses: tt.statuses}
+			svr := httptest.NewServer(iss)
+			defer svr.Close()
+
+			sut := grappa.NewClientCredentials(svr.URL, "client", tt.secret, func(o *grappa.ClientCredentialsOptions) {
+				o.Client = svr.Client()
+				o.Scopes = []string{"default"}
+				o.Backoff = time.Millisecond
+			})
+			tt.setup(sut)
+
+			var err error
+			for _, m := range tt.methods {
+				var tok grappa.Token
+				if tok, err = sut.Token(context.Background(), m); err == nil && tok.AccessToken == "" {
+					t.Error("got empty token, expected a token")
+				}
+			}
+
+			assertErrorExists(t, err, tt.err)
+			assertDeepEqual(t, iss.requests, tt.exp)
+		})
+	}
+}
+
+func TestClientCredentials_Interceptor(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*grappa.ClientCredentials)
+		err   bool
+	}{
+		{
+			name:  "should return an error if the token does not have the required scope",
+			setup: func(*grappa.ClientCredentials) {},
+			err:   true,
+		},
+		{
+			name: "should request tokens with the required scope",
+			setup: func(c *grappa.ClientCredentials) {
+				c.Register("/grpc.health.v1.Health/*", &grappapb.Rule{RequireScope: []string{"health"}})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := []byte("secretkey")
+			svr := httptest.NewServer(&testIssuer{key: key})
+			defer svr.Close()
+
+			src := grappa.NewClientCredentials(svr.URL, "client", "secret", func(o *grappa.ClientCredentialsOptions) {
+				o.Client = svr.Client()
+			})
+			tt.setup(src)
+
+			auth := grappa.New(grappa.HMAC(key), func(o *grappa.Options) {
+				o.ClaimsVerifiers = append(o.ClaimsVerifiers, grappa.VerifyScope())
+			})
+			auth.Register("/grpc.health.v1.Health/*", &grappapb.Rule{RequireScope: []string{"health"}})
+
+			cli := newHealthClient(t, auth, grpc.WithUnaryInterceptor(grappa.UnaryClientInterceptor(src)))
+
+			_, err := cli.Check(context.Background(), new(grpc_health_v1.HealthCheckRequest))
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
+
+func TestClientCredentials_Concurrency(t *testing.T) {
+	t.Run("should not block token requests for other scopes", func(t *testing.T) {
+		iss := &testIssuer{key: []byte("secretkey")}
+		fetching, release := make(chan struct{}), make(chan struct{})
+
+		svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.FormValue("scope") == "slow" {
+				close(fetching)
+				<-release
+			}
+			iss.ServeHTTP(w, r)
+		}))
+		defer svr.Close()
+
+		sut := grappa.NewClientCredentials(svr.URL, "client", "secret", func(o *grappa.ClientCredentialsOptions) {
+			o.Client = svr.Client()
+		})
+		sut.Register("/package.Service/Slow", &grappapb.Rule{RequireScope: []string{"slow"}})
+		sut.Register("/package.Service/Fast", &grappapb.Rule{RequireScope: []string{"fast"}})
+
+		slow := make(chan error)
+		go func() {
+			_, err := sut.Token(context.Background(), "/package.Service/Slow")
+			slow <- err
+		}()
+		<-fetching
+
+		fast := make(chan error)
+		go func() {
+			_, err := sut.Token(context.Background(), "/package.Service/Fast")
+			fast <- err
+		}()
+
+		select {
+		case err := <-fast:
+			assertErrorExists(t, err, false)
+		case <-time.After(time.Second):
+			t.Error("token request blocked by a fetch for other scopes")
+		}
+
+		close(release)
+		assertErrorExists(t, <-slow, false)
+	})
+
+	t.Run("should fetch tokens for the same scopes once", func(t *testing.T) {
+		iss := &testIssuer{key: []byte("secretkey")}
+		release := make(chan struct{})
+
+		svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			iss.ServeHTTP(w, r)
+		}))
+		defer svr.Close()
+
+		sut := grappa.NewClientCredentials(svr.URL, "client", "secret", func(o *grappa.ClientCredentialsOptions) {
+			o.Client = svr.Client()
+			o.Scopes = []string{"default"}
+		})
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 5)
+		for i := 0; i < cap(errs); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := sut.Token(context.Background(), "/package.Service/Method")
+				errs <- err
+			}()
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			assertErrorExists(t, err, false)
+		}
+		assertDeepEqual(t, iss.requests, []string{"default"})
+	})
+}
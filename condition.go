@@ -0,0 +1,81 @@
+package grappa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/stevecallear/grappa/internal/condition"
+	"github.com/stevecallear/grappa/proto/grappapb"
+)
+
+// compileCondition compiles the rule condition for the specified pattern
+// the request variable is typed using the method input message if the pattern
+// is an exact method that is present in the global proto registry
+func compileCondition(pattern string, r *grappapb.Rule) (cel.Program, error) {
+	e := r.GetCondition()
+	if e == "" {
+		return nil, nil
+	}
+
+	prg, err := condition.Compile(e, inputDescriptor(pattern))
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition for pattern %s: %v", pattern, err)
+	}
+
+	return prg, nil
+}
+
+// evaluateCondition evaluates the rule condition for the authorized request context
+func (a *Authorizor) evaluateCondition(ctx context.Context, req interface{}) error {
+	rctx, _ := FromContext(ctx)
+	if rctx.condition == nil {
+		return nil
+	}
+
+	claims, _ := ClaimsFromContext(ctx)
+
+	ok, err := condition.Eval(rctx.condition, claims, rctx.FullMethod, req)
+	if err != nil {
+		return a.opts.ErrorFn(rctx, fmt.Errorf("condition evaluation failed: %v", err))
+	}
+
+	if !ok {
+		return a.opts.ErrorFn(rctx, errors.New("condition not satisfied"))
+	}
+
+	return nil
+}
+
+func inputDescriptor(pattern string) protoreflect.MessageDescriptor {
+	if strings.HasSuffix(pattern, "*") {
+		return nil
+	}
+
+	i := strings.LastIndex(pattern, "/")
+	if i < 1 {
+		return nil
+	}
+
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(pattern[1:i]))
+	if err != nil {
+		return nil
+	}
+
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil
+	}
+
+	md := sd.Methods().ByName(protoreflect.Name(pattern[i+1:]))
+	if md == nil {
+		return nil
+	}
+
+	return md.Input()
+}
@@ -0,0 +1,154 @@
+package grappa_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/stevecallear/grappa"
+	"github.com/stevecallear/grappa/proto/grappapb"
+)
+
+func TestCondition_UnaryInterceptor(t *testing.T) {
+	const checkMethod = "/grpc.health.v1.Health/Check"
+
+	claims := jwt.MapClaims{
+		"sub":   "subject",
+		"roles": []string{"user"},
+	}
+
+	tests := []struct {
+		name      string
+		pattern   string
+		method    string
+		condition string
+		claims    jwt.MapClaims
+		req       interface{}
+		err       bool
+	}{
+		{
+			name:      "should return an error if the condition is not satisfied",
+			pattern:   "/package.Service/Method",
+			method:    "/package.Service/Method",
+			condition: "'admin' in claims.roles",
+			claims:    claims,
+			err:       true,
+		},
+		{
+			name:      "should return an error if the condition cannot be evaluated",
+			pattern:   "/package.Service/Method",
+			method:    "/package.Service/Method",
+			condition: "claims.tenant == 'tenant'",
+			claims:    claims,
+			err:       true,
+		},
+		{
+			name:      "should return an error if the request condition is not satisfied",
+			pattern:   checkMethod,
+			method:    checkMethod,
+			condition: "request.service == claims.sub",
+			claims:    claims,
+			req:       &grpc_health_v1.HealthCheckRequest{Service: "other"},
+			err:       true,
+		},
+		{
+			name:      "should allow requests if the condition is satisfied",
+			pattern:   "/package.Service/Method",
+			method:    "/package.Service/Method",
+			condition: "claims.sub == 'subject' && 'user' in claims.roles",
+			claims:    claims,
+		},
+		{
+			name:      "should evaluate the method",
+			pattern:   "/package.Service/*",
+			method:    "/package.Service/Method",
+			condition: "method.endsWith('/Method')",
+			claims:    claims,
+		},
+		{
+			name:      "should evaluate the request",
+			pattern:   checkMethod,
+			method:    checkMethod,
+			condition: "request.service == claims.sub",
+			claims:    claims,
+			req:       &grpc_health_v1.HealthCheckRequest{Service: "subject"},
+		},
+		{
+			name:      "should evaluate conditions for anonymous requests",
+			pattern:   "/package.Service/Method",
+			method:    "/package.Service/Method",
+			condition: "!has(claims.sub)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := grappa.New(grappa.HMAC([]byte("secretkey")))
+			sut.Register(tt.pattern, &grappapb.Rule{
+				AllowAnonymous: tt.claims == nil,
+				Condition:      tt.condition,
+			})
+
+			info := &grpc.UnaryServerInfo{FullMethod: tt.method}
+			_, err := sut.UnaryInterceptor(newTokenContext(tt.claims), tt.req, info, func(context.Context, interface{}) (interface{}, error) {
+				return nil, nil
+			})
+
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
+
+func TestCondition_Register(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		condition string
+		err       bool
+	}{
+		{
+			name:      "should return an error if the condition is invalid",
+			pattern:   "/package.Service/Method",
+			condition: "claims.sub ==",
+			err:       true,
+		},
+		{
+			name:      "should return an error if the condition does not return a bool",
+			pattern:   "/package.Service/Method",
+			condition: "'sub: ' + method",
+			err:       true,
+		},
+		{
+			name:      "should return an error if the request field does not exist",
+			pattern:   "/grpc.health.v1.Health/Check",
+			condition: "request.invalid == claims.sub",
+			err:       true,
+		},
+		{
+			name:      "should return an error if the request type cannot be resolved",
+			pattern:   "/grpc.health.v1.Health/*",
+			condition: "request.service == claims.sub",
+			err:       true,
+		},
+		{
+			name:      "should compile valid conditions",
+			pattern:   "/grpc.health.v1.Health/Check",
+			condition: "request.service == claims.sub",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := grappa.New(grappa.HMAC([]byte("secretkey")))
+
+			err := sut.Load(map[string]*grappapb.Rule{
+				tt.pattern: {Condition: tt.condition},
+			})
+
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package grappa
+
+import (
+	"context"
+
+	"github.com/golang-jwt/jwt"
+)
+
+type contextKey int
+
+const (
+	contextKeyContext contextKey = iota
+	contextKeyClaims
+	contextKeyCustomClaims
+)
+
+// FromContext returns the authorizor request context
+func FromContext(ctx context.Context) (Context, bool) {
+	c, ok := ctx.Value(contextKeyContext).(Context)
+	return c, ok
+}
+
+// ClaimsFromContext returns the verified token claims
+// the claims will not be present for anonymous requests
+func ClaimsFromContext(ctx context.Context) (jwt.MapClaims, bool) {
+	c, ok := ctx.Value(contextKeyClaims).(jwt.MapClaims)
+	return c, ok
+}
+
+// SubjectFromContext returns the verified token subject claim
+func SubjectFromContext(ctx context.Context) (string, bool) {
+	c, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	s, ok := c["sub"].(string)
+	return s, ok
+}
+
+// CustomClaimsFromContext returns the verified token claims decoded into the
+// type configured using grappa.CustomClaims
+func CustomClaimsFromContext(ctx context.Context) (jwt.Claims, bool) {
+	c, ok := ctx.Value(contextKeyCustomClaims).(jwt.Claims)
+	return c, ok
+}
+
+func newContext(ctx context.Context, rctx Context, c jwt.MapClaims, cc jwt.Claims) context.Context {
+	ctx = context.WithValue(ctx, contextKeyContext, rctx)
+	if c != nil {
+		ctx = context.WithValue(ctx, contextKeyClaims, c)
+	}
+
+	if cc != nil {
+		ctx = context.WithValue(ctx, contextKeyCustomClaims, cc)
+	}
+
+	return ctx
+}
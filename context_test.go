@@ -0,0 +1,203 @@
+package grappa_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/stevecallear/grappa"
+	"github.com/stevecallear/grappa/proto/grappapb"
+)
+
+func TestClaimsFromContext(t *testing.T) {
+	now := time.Now().UTC()
+	info := &grpc.UnaryServerInfo{
+		FullMethod: "/package.Service/Method",
+	}
+
+	tests := []struct {
+		name   string
+		rule   *grappapb.Rule
+		token  string
+		assert func(*testing.T, context.Context)
+	}{
+		{
+			name: "should attach the request context for anonymous requests",
+			rule: &grappapb.Rule{AllowAnonymous: true},
+			assert: func(t *testing.T, ctx context.Context) {
+				rctx, ok := grappa.FromContext(ctx)
+				if !ok || rctx.ID == "" || rctx.FullMethod != info.FullMethod || !rctx.Rule.AllowAnonymous {
+					t.Errorf("got %v, expected a valid request context", rctx)
+				}
+
+				if c, ok := grappa.ClaimsFromContext(ctx); ok {
+					t.Errorf("got %v, expected no claims", c)
+				}
+
+				if s, ok := grappa.SubjectFromContext(ctx); ok {
+					t.Errorf("got %s, expected no subject", s)
+				}
+			},
+		},
+		{
+			name: "should attach the verified claims",
+			rule: &grappapb.Rule{},
+			token: newHMAC([]byte("secretkey"), jwt.MapClaims{
+				"sub":   "subject",
+				"exp":   now.Add(time.Hour).Unix(),
+				"roles": []string{"admin"},
+			}),
+			assert: func(t *testing.T, ctx context.Context) {
+				rctx, ok := grappa.FromContext(ctx)
+				if !ok || rctx.ID == "" || rctx.FullMethod != info.FullMethod {
+					t.Errorf("got %v, expected a valid request context", rctx)
+				}
+
+				c, ok := grappa.ClaimsFromContext(ctx)
+				if !ok {
+					t.Fatal("got no claims, expected claims")
+				}
+				assertDeepEqual(t, c["roles"], []interface{}{"admin"})
+
+				s, ok := grappa.SubjectFromContext(ctx)
+				if !ok || s != "subject" {
+					t.Errorf("got %s, expected subject", s)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := grappa.New(grappa.HMAC([]byte("secretkey")))
+			sut.Register(info.FullMethod, tt.rule)
+
+			md := metadata.MD{}
+			if tt.token != "" {
+				md.Set("authorization", "Bearer "+tt.token)
+			}
+
+			ctx := metadata.NewIncomingContext(context.Background(), md)
+			_, err := sut.UnaryInterceptor(ctx, nil, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+				tt.assert(t, ctx)
+				return nil, nil
+			})
+
+			assertErrorExists(t, err, false)
+		})
+	}
+}
+
+type customClaims struct {
+	jwt.StandardClaims
+	Tier string `json:"tier"`
+}
+
+func TestCustomClaimsFromContext(t *testing.T) {
+	now := time.Now().UTC()
+	info := &grpc.UnaryServerInfo{
+		FullMethod: "/package.Service/Method",
+	}
+
+	tests := []struct {
+		name      string
+		claims    jwt.MapClaims
+		verifiers []grappa.CustomVerifyFunc
+		exp       *customClaims
+		err       bool
+	}{
+		{
+			name: "should return an error if the custom claims are invalid",
+			claims: jwt.MapClaims{
+				"sub":  "subject",
+				"tier": 1,
+				"exp":  now.Add(time.Hour).Unix(),
+			},
+			err: true,
+		},
+		{
+			name: "should return an error if a custom verifier fails",
+			claims: jwt.MapClaims{
+				"sub":  "subject",
+				"tier": "basic",
+				"exp":  now.Add(time.Hour).Unix(),
+			},
+			verifiers: []grappa.CustomVerifyFunc{
+				func(_ grappa.Context, c jwt.Claims) error {
+					if c.(*customClaims).Tier != "premium" {
+						return errors.New("invalid tier")
+					}
+					return nil
+				},
+			},
+			err: true,
+		},
+		{
+			name: "should attach the custom claims",
+			claims: jwt.MapClaims{
+				"sub":  "subject",
+				"tier": "premium",
+				"exp":  now.Add(time.Hour).Unix(),
+			},
+			exp: &customClaims{
+				StandardClaims: jwt.StandardClaims{
+					Subject:   "subject",
+					ExpiresAt: now.Add(time.Hour).Unix(),
+				},
+				Tier: "premium",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := grappa.New(
+				grappa.HMAC([]byte("secretkey")),
+				grappa.CustomClaims(func() jwt.Claims { return new(customClaims) }, tt.verifiers...))
+
+			sut.Register(info.FullMethod, &grappapb.Rule{})
+
+			md := metadata.Pairs("authorization", "Bearer "+newHMAC([]byte("secretkey"), tt.claims))
+			ctx := metadata.NewIncomingContext(context.Background(), md)
+
+			_, err := sut.UnaryInterceptor(ctx, nil, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+				c, ok := grappa.CustomClaimsFromContext(ctx)
+				if !ok {
+					t.Fatal("got no claims, expected claims")
+				}
+
+				assertDeepEqual(t, c, tt.exp)
+				return nil, nil
+			})
+
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	t.Run("should return false if the context has not been authorized", func(t *testing.T) {
+		ctx := context.Background()
+
+		if _, ok := grappa.FromContext(ctx); ok {
+			t.Error("got true, expected false")
+		}
+
+		if _, ok := grappa.ClaimsFromContext(ctx); ok {
+			t.Error("got true, expected false")
+		}
+
+		if _, ok := grappa.SubjectFromContext(ctx); ok {
+			t.Error("got true, expected false")
+		}
+
+		if _, ok := grappa.CustomClaimsFromContext(ctx); ok {
+			t.Error("got true, expected false")
+		}
+	})
+}
@@ -3,9 +3,12 @@ module github.com/stevecallear/grappa
 go 1.16
 
 require (
-	github.com/golang-jwt/jwt v3.2.1+incompatible
+	github.com/golang-jwt/jwt v3.2.2+incompatible
+	github.com/google/cel-go v0.9.0
 	github.com/google/uuid v1.3.0
 	github.com/lyft/protoc-gen-star v0.5.3
-	google.golang.org/grpc v1.39.0
+	google.golang.org/genproto v0.0.0-20210831024726-fe130286e0e2
+	google.golang.org/grpc v1.40.0
 	google.golang.org/protobuf v1.27.1
+	sigs.k8s.io/yaml v1.3.0
 )
@@ -1,13 +1,59 @@
 package grappa_test
 
 import (
+	"context"
+	"io"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
+type serverStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	msgs []interface{}
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	if len(s.msgs) < 1 {
+		return io.EOF
+	}
+
+	if pm, ok := m.(proto.Message); ok {
+		proto.Merge(pm, s.msgs[0].(proto.Message))
+	} else {
+		reflect.ValueOf(m).Elem().Set(reflect.ValueOf(s.msgs[0]))
+	}
+
+	s.msgs = s.msgs[1:]
+	return nil
+}
+
+// newTokenContext returns an incoming context with a bearer token for the claims
+// signed using the "secretkey" hmac key, or without a token if the claims are nil
+func newTokenContext(claims jwt.MapClaims) context.Context {
+	md := metadata.MD{}
+	if claims != nil {
+		c := jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()}
+		for k, v := range claims {
+			c[k] = v
+		}
+		md.Set("authorization", "Bearer "+newHMAC([]byte("secretkey"), c))
+	}
+
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
 func newNone(c jwt.MapClaims) string {
 	t, err := jwt.NewWithClaims(jwt.SigningMethodNone, c).SignedString(nil)
 	if err != nil {
@@ -40,6 +86,18 @@ func newHMAC(k []byte, c jwt.MapClaims) string {
 	return t
 }
 
+func newToken(m jwt.SigningMethod, kid string, k interface{}, c jwt.MapClaims) string {
+	tok := jwt.NewWithClaims(m, c)
+	tok.Header["kid"] = kid
+
+	t, err := tok.SignedString(k)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
 func assertErrorExists(t *testing.T, act error, exp bool) {
 	if act != nil && !exp {
 		t.Errorf("got %v, expected nil", act)
@@ -0,0 +1,212 @@
+// Package grappatest provides token issuing and server utilities for testing
+// grappa authorization end-to-end
+package grappatest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/stevecallear/grappa"
+)
+
+type (
+	// Issuer represents a test token issuer
+	Issuer struct {
+		issuer   string
+		audience string
+		keyID    string
+		key      *rsa.PrivateKey
+	}
+
+	// Server represents an in-memory grpc test server
+	Server struct {
+		svr *grpc.Server
+		lis *bufconn.Listener
+	}
+)
+
+// NewIssuer returns a new issuer for the specified issuer and audience claims
+// a new RSA key is generated for each issuer
+func NewIssuer(iss, aud string) (*Issuer, error) {
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	kid := make([]byte, 8)
+	if _, err = rand.Read(kid); err != nil {
+		return nil, err
+	}
+
+	return &Issuer{
+		issuer:   iss,
+		audience: aud,
+		keyID:    hex.EncodeToString(kid),
+		key:      k,
+	}, nil
+}
+
+// Token returns a signed token containing the standard iss, aud, iat, nbf and exp claims
+// and the specified claims. The token expires after one hour unless otherwise specified.
+func (i *Issuer) Token(claimFns ...func(jwt.MapClaims)) (string, error) {
+	now := time.Now().UTC()
+	c := jwt.MapClaims{
+		"iss": i.issuer,
+		"aud": i.audience,
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+
+	for _, fn := range claimFns {
+		fn(c)
+	}
+
+	t := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+	t.Header["kid"] = i.keyID
+
+	return t.SignedString(i.key)
+}
+
+// MustToken returns a signed token, panicking on error
+func (i *Issuer) MustToken(claimFns ...func(jwt.MapClaims)) string {
+	t, err := i.Token(claimFns...)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+// TokenSource returns a token source that issues a new token with the specified claims
+func (i *Issuer) TokenSource(claimFns ...func(jwt.MapClaims)) grappa.TokenSource {
+	return grappa.TokenSourceFunc(func(context.Context, string) (grappa.Token, error) {
+		t, err := i.Token(claimFns...)
+		if err != nil {
+			return grappa.Token{}, err
+		}
+
+		return grappa.Token{AccessToken: t}, nil
+	})
+}
+
+// KeyID returns the issuer key id
+func (i *Issuer) KeyID() string {
+	return i.keyID
+}
+
+// PublicKey returns the issuer public key
+func (i *Issuer) PublicKey() *rsa.PublicKey {
+	return &i.key.PublicKey
+}
+
+// JWKS returns the json web key set containing the issuer public key
+func (i *Issuer) JWKS() []byte {
+	enc := base64.RawURLEncoding.EncodeToString
+
+	b, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": i.keyID,
+				"alg": jwt.SigningMethodRS256.Alg(),
+				"use": "sig",
+				"n":   enc(i.key.N.Bytes()),
+				"e":   enc(big.NewInt(int64(i.key.E)).Bytes()),
+			},
+		},
+	})
+
+	return b
+}
+
+// JWKSource returns a json web key set source for the issuer
+func (i *Issuer) JWKSource() grappa.JWKSource {
+	return func() ([]byte, error) {
+		return i.JWKS(), nil
+	}
+}
+
+// Authorizor returns a new authorizor that verifies tokens using the issuer key set
+// and claims, along with any specified options
+func (i *Issuer) Authorizor(optFns ...func(*grappa.Options)) *grappa.Authorizor {
+	fns := append([]func(*grappa.Options){
+		grappa.JWKS(i.JWKSource()),
+		grappa.VerifyClaims(i.issuer, i.audience),
+	}, optFns...)
+
+	return grappa.New(fns...)
+}
+
+// Subject sets the sub claim
+func Subject(sub string) func(jwt.MapClaims) {
+	return Claim("sub", sub)
+}
+
+// Scope sets the space delimited scope claim
+func Scope(scopes ...string) func(jwt.MapClaims) {
+	return Claim("scope", strings.Join(scopes, " "))
+}
+
+// ExpiresIn sets the exp claim relative to the current time
+// a negative duration can be used to issue expired tokens
+func ExpiresIn(d time.Duration) func(jwt.MapClaims) {
+	return func(c jwt.MapClaims) {
+		c["exp"] = time.Now().UTC().Add(d).Unix()
+	}
+}
+
+// Claim sets the specified claim
+func Claim(name string, v interface{}) func(jwt.MapClaims) {
+	return func(c jwt.MapClaims) {
+		c[name] = v
+	}
+}
+
+// NewServer returns a new in-memory grpc server using the authorizor interceptors
+// services should be registered using the register func
+func NewServer(a *grappa.Authorizor, register func(*grpc.Server), opts ...grpc.ServerOption) *Server {
+	opts = append([]grpc.ServerOption{
+		grpc.UnaryInterceptor(a.UnaryInterceptor),
+		grpc.StreamInterceptor(a.StreamInterceptor),
+	}, opts...)
+
+	s := &Server{
+		svr: grpc.NewServer(opts...),
+		lis: bufconn.Listen(1024 * 1024),
+	}
+
+	register(s.svr)
+	go s.svr.Serve(s.lis)
+
+	return s
+}
+
+// Dial returns a new insecure client connection to the server
+func (s *Server) Dial(opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.lis.Dial()
+		}),
+	}, opts...)
+
+	return grpc.Dial("bufnet", opts...)
+}
+
+// Close stops the server
+func (s *Server) Close() {
+	s.svr.Stop()
+}
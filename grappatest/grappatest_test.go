@@ -0,0 +1,126 @@
+package grappatest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/stevecallear/grappa"
+	"github.com/stevecallear/grappa/grappatest"
+	"github.com/stevecallear/grappa/proto/grappapb"
+)
+
+func registerHealthRules(a grappa.Registry) {
+	a.Register("/grpc.health.v1.Health/Check", &grappapb.Rule{
+		RequireScope: []string{"health"},
+	})
+}
+
+func TestServer(t *testing.T) {
+	iss, err := grappatest.NewIssuer("issuer", "audience")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := grappatest.NewIssuer("issuer", "audience")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		src  grappa.TokenSource
+		exp  codes.Code
+	}{
+		{
+			name: "should reject tokens signed by another issuer",
+			src:  other.TokenSource(grappatest.Subject("subject"), grappatest.Scope("health")),
+			exp:  codes.Unauthenticated,
+		},
+		{
+			name: "should reject expired tokens",
+			src:  iss.TokenSource(grappatest.Scope("health"), grappatest.ExpiresIn(-time.Minute)),
+			exp:  codes.Unauthenticated,
+		},
+		{
+			name: "should reject tokens without the required scope",
+			src:  iss.TokenSource(grappatest.Scope("other")),
+			exp:  codes.Unauthenticated,
+		},
+		{
+			name: "should reject tokens with an invalid audience",
+			src:  iss.TokenSource(grappatest.Scope("health"), grappatest.Claim("aud", "other")),
+			exp:  codes.Unauthenticated,
+		},
+		{
+			name: "should accept valid tokens",
+			src:  iss.TokenSource(grappatest.Subject("subject"), grappatest.Scope("user", "health")),
+			exp:  codes.OK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := iss.Authorizor()
+			registerHealthRules(auth)
+
+			svr := grappatest.NewServer(auth, func(s *grpc.Server) {
+				grpc_health_v1.RegisterHealthServer(s, health.NewServer())
+			})
+			defer svr.Close()
+
+			conn, err := svr.Dial(grpc.WithUnaryInterceptor(grappa.UnaryClientInterceptor(tt.src)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			_, err = grpc_health_v1.NewHealthClient(conn).Check(context.Background(), new(grpc_health_v1.HealthCheckRequest))
+			if act := status.Code(err); act != tt.exp {
+				t.Errorf("got %v, expected %v", act, tt.exp)
+			}
+		})
+	}
+}
+
+func TestIssuer_Token(t *testing.T) {
+	iss, err := grappatest.NewIssuer("issuer", "audience")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should issue tokens with standard and specified claims", func(t *testing.T) {
+		tok := iss.MustToken(grappatest.Subject("subject"), grappatest.Claim("tier", "premium"))
+
+		c := jwt.MapClaims{}
+		pt, err := jwt.ParseWithClaims(tok, c, func(*jwt.Token) (interface{}, error) {
+			return iss.PublicKey(), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if act, exp := pt.Header["kid"], iss.KeyID(); act != exp {
+			t.Errorf("got %v, expected %s", act, exp)
+		}
+
+		for k, exp := range map[string]interface{}{"iss": "issuer", "aud": "audience", "sub": "subject", "tier": "premium"} {
+			if act := c[k]; act != exp {
+				t.Errorf("got %v, expected %v", act, exp)
+			}
+		}
+
+		for _, k := range []string{"iat", "nbf", "exp"} {
+			if _, ok := c[k]; !ok {
+				t.Errorf("got no %s claim, expected a value", k)
+			}
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package condition
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Compile compiles the condition expression
+// the claims and method variables are always declared, while the request variable
+// is only declared if the request message descriptor is specified
+func Compile(expr string, req protoreflect.MessageDescriptor) (cel.Program, error) {
+	opts := []cel.EnvOption{}
+	ds := []*exprpb.Decl{
+		decls.NewVar("claims", decls.NewMapType(decls.String, decls.Dyn)),
+		decls.NewVar("method", decls.String),
+	}
+
+	if req != nil {
+		opts = append(opts, cel.TypeDescs(files(req.ParentFile(), map[string]bool{})...))
+		ds = append(ds, decls.NewVar("request", decls.NewObjectType(string(req.FullName()))))
+	}
+
+	env, err := cel.NewEnv(append(opts, cel.Declarations(ds...))...)
+	if err != nil {
+		return nil, err
+	}
+
+	ast, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		es := make([]string, 0, len(iss.Errors()))
+		for _, e := range iss.Errors() {
+			es = append(es, fmt.Sprintf("%d:%d: %s", e.Location.Line(), e.Location.Column()+1, e.Message))
+		}
+		return nil, errors.New(strings.Join(es, "; "))
+	}
+
+	if t := ast.ResultType(); !proto.Equal(t, decls.Bool) && !proto.Equal(t, decls.Dyn) {
+		return nil, errors.New("expression must return a bool")
+	}
+
+	return env.Program(ast)
+}
+
+// Eval evaluates the compiled condition against the specified variables
+func Eval(prg cel.Program, claims map[string]interface{}, method string, req interface{}) (bool, error) {
+	if claims == nil {
+		claims = map[string]interface{}{}
+	}
+
+	v, _, err := prg.Eval(map[string]interface{}{
+		"claims":  claims,
+		"method":  method,
+		"request": req,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression returned %s, expected bool", v.Type().TypeName())
+	}
+
+	return b, nil
+}
+
+// files returns the file and its transitive imports, as the cel type
+// registry does not resolve imported message types
+func files(fd protoreflect.FileDescriptor, seen map[string]bool) []interface{} {
+	if seen[fd.Path()] {
+		return nil
+	}
+	seen[fd.Path()] = true
+
+	fs := []interface{}{fd}
+	for i := 0; i < fd.Imports().Len(); i++ {
+		fs = append(fs, files(fd.Imports().Get(i).FileDescriptor, seen)...)
+	}
+
+	return fs
+}
@@ -1,15 +1,24 @@
 package generator
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"text/template"
 
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"sigs.k8s.io/yaml"
 
 	pgs "github.com/lyft/protoc-gen-star"
 	pgsgo "github.com/lyft/protoc-gen-star/lang/go"
 
+	"github.com/stevecallear/grappa/internal/condition"
+	"github.com/stevecallear/grappa/internal/validate"
 	"github.com/stevecallear/grappa/proto/grappapb"
 )
 
@@ -17,8 +26,10 @@ type (
 	// Generator represents a protoc generator module
 	Generator struct {
 		*pgs.ModuleBase
-		ctx pgsgo.Context
-		tpl *template.Template
+		ctx          pgsgo.Context
+		tpl          *template.Template
+		manifest     string
+		requireRules bool
 	}
 
 	file struct {
@@ -29,38 +40,84 @@ type (
 
 	service struct {
 		Name    string
+		GoName  string
 		Methods []method
 	}
 
 	method struct {
-		Pattern string
+		Pattern   string
+		Var       string
+		Streaming grappapb.MethodRule_Streaming
+		Source    string
 		*grappapb.Rule
 	}
 )
 
+const (
+	manifestJSON = "json"
+	manifestYAML = "yaml"
+)
+
 const templateStr = `// Code generated by protoc-gen-grappa. DO NOT EDIT.
 // source: {{ .InputPath }}
 
 package {{ .Package }}
 
 import (
+	"google.golang.org/protobuf/proto"
+
 	"github.com/stevecallear/grappa"
 	"github.com/stevecallear/grappa/proto/grappapb"
 )
 
 {{ range .Services }}
-func Register{{ .Name }}Rules(a grappa.Registry){
 {{ range .Methods }}
-	a.Register("{{ .Pattern }}", &grappapb.Rule{
-		AllowAnonymous: {{ .AllowAnonymous }},
-		RequireScope: []string{
-			{{ range .RequireScope }}"{{ . }}",
-			{{ end }}
+// {{ .Var }} is the rule for {{ .Pattern }}
+var {{ .Var }} = &grappapb.Rule{
+	AllowAnonymous: {{ .AllowAnonymous }},
+	RequireScope: []string{
+		{{ range .RequireScope }}"{{ . }}",
+		{{ end }}
+	},{{ if .ScopeExpression }}
+	ScopeExpression: {{ printf "%q" .ScopeExpression }},{{ end }}{{ if .RequireClaim }}
+	RequireClaim: []*grappapb.ClaimRequirement{
+		{{ range .RequireClaim }}{
+			Claim: {{ printf "%q" .Claim }},
+			Operator: grappapb.ClaimRequirement_{{ .Operator }},
+			Values: []string{
+				{{ range .Values }}{{ printf "%q" . }},
+				{{ end }}
+			},
 		},
-	})
-{{ end }}
+		{{ end }}
+	},{{ end }}{{ if .RequireRole }}
+	RequireRole: []string{
+		{{ range .RequireRole }}{{ printf "%q" . }},
+		{{ end }}
+	},{{ end }}{{ if .Policy }}
+	Policy: {{ printf "%q" .Policy }},{{ end }}{{ if .BindField }}
+	BindField: []*grappapb.FieldBinding{
+		{{ range .BindField }}{
+			Field: {{ printf "%q" .Field }},
+			Claim: {{ printf "%q" .Claim }},
+			Operator: grappapb.FieldBinding_{{ .Operator }},
+		},
+		{{ end }}
+	},{{ end }}{{ if .Condition }}
+	Condition: {{ printf "%q" .Condition }},{{ end }}
 }
 {{ end }}
+// {{ .GoName }}Rules contains the {{ .GoName }} rules keyed by full method name
+var {{ .GoName }}Rules = map[string]*grappapb.Rule{
+{{ range .Methods }}	"{{ .Pattern }}": {{ .Var }},
+{{ end }}}
+
+// Register{{ .Name }}Rules registers copies of the {{ .GoName }} rules, so that
+// changes to the exported values do not affect authorization
+func Register{{ .Name }}Rules(a grappa.Registry){
+{{ range .Methods }}	a.Register("{{ .Pattern }}", proto.Clone({{ .Var }}).(*grappapb.Rule))
+{{ end }}}
+{{ end }}
 `
 
 // New returns a new protoc generator module
@@ -85,16 +142,71 @@ func (m *Generator) InitContext(c pgs.BuildContext) {
 
 // Execute executes the generator module
 func (m *Generator) Execute(targets map[string]pgs.File, pkgs map[string]pgs.Package) []pgs.Artifact {
+	if err := m.parseParams(); err != nil {
+		m.AddError(err.Error())
+		return m.Artifacts()
+	}
+
 	for _, f := range targets {
 		if fd, ok := m.describeFile(f); ok {
 			n := m.ctx.OutputPath(f).SetExt(".grappa.go")
 			m.AddGeneratorTemplateFile(n.String(), m.tpl, &fd)
+
+			if m.manifest != "" {
+				m.addManifest(f, fd)
+			}
 		}
 	}
 
 	return m.Artifacts()
 }
 
+// parseParams parses the generator parameters
+// manifest optionally specifies a rule manifest format, either json or yaml
+// require_rules specifies whether methods without a rule in scope are errors
+func (m *Generator) parseParams() error {
+	p := m.Parameters()
+
+	m.manifest = p.Str("manifest")
+	switch m.manifest {
+	case "", manifestJSON, manifestYAML:
+	default:
+		return fmt.Errorf("invalid manifest format: %s", m.manifest)
+	}
+
+	var err error
+	if m.requireRules, err = p.Bool("require_rules"); err != nil {
+		return fmt.Errorf("invalid require_rules value: %v", err)
+	}
+
+	return nil
+}
+
+// addManifest adds a rule manifest for the file that can be audited
+// or read by the rule loader
+func (m *Generator) addManifest(f pgs.File, fd file) {
+	rs := &grappapb.RuleSet{}
+	for _, sd := range fd.Services {
+		for _, md := range sd.Methods {
+			rs.Methods = append(rs.Methods, &grappapb.MethodRule{
+				FullMethod: md.Pattern,
+				Streaming:  md.Streaming,
+				Rule:       md.Rule,
+				Source:     md.Source,
+			})
+		}
+	}
+
+	b, err := marshalManifest(rs, m.manifest)
+	if err != nil {
+		m.AddError(fmt.Sprintf("%s: %v", fd.InputPath, err))
+		return
+	}
+
+	n := m.ctx.OutputPath(f).SetExt(".grappa." + m.manifest)
+	m.AddGeneratorFile(n.String(), string(b))
+}
+
 func (m *Generator) describeFile(f pgs.File) (file, bool) {
 	fd := file{
 		InputPath: f.InputPath().String(),
@@ -114,6 +226,7 @@ func (m *Generator) describeFile(f pgs.File) (file, bool) {
 func (m *Generator) describeService(s pgs.Service) (service, bool) {
 	sd := service{
 		Name:    m.ctx.Name(s).String(),
+		GoName:  pgsgo.PGGUpperCamelCase(s.Name()).String(),
 		Methods: []method{},
 	}
 
@@ -127,19 +240,256 @@ func (m *Generator) describeService(s pgs.Service) (service, bool) {
 }
 
 func (m *Generator) describeMethod(me pgs.Method) (method, bool) {
-	o := me.Descriptor().Options
-	if !proto.HasExtension(o, grappapb.E_Rule) {
+	r, ok := methodRule(me)
+	if !ok {
+		if m.requireRules {
+			m.AddError(fmt.Sprintf("%s: %s: rule not specified", location(me), methodPattern(me)))
+		}
+		return method{}, false
+	}
+
+	if err := validate.Rule(r); err != nil {
+		m.AddError(fmt.Sprintf("%s: %s: %v", location(me), methodPattern(me), err))
 		return method{}, false
 	}
 
-	r := proto.GetExtension(o, grappapb.E_Rule).(*grappapb.Rule)
+	for _, b := range r.GetBindField() {
+		if err := validateFieldBinding(me.Input(), b); err != nil {
+			m.AddError(fmt.Sprintf("%s: %s: invalid field binding: %v", location(me), methodPattern(me), err))
+			return method{}, false
+		}
+	}
+
+	if e := r.GetCondition(); e != "" {
+		if err := checkCondition(me, e); err != nil {
+			m.AddError(fmt.Sprintf("%s: %s: invalid condition: %v", location(me), methodPattern(me), err))
+			return method{}, false
+		}
+	}
+
+	for _, w := range ruleWarnings(r) {
+		m.Logf("warning: %s: %s: %s", location(me), methodPattern(me), w)
+	}
 
 	return method{
-		Pattern: methodPattern(me),
-		Rule:    r,
+		Pattern:   methodPattern(me),
+		Var:       fmt.Sprintf("%s_%s_Rule", pgsgo.PGGUpperCamelCase(me.Service().Name()), m.ctx.Name(me)),
+		Streaming: streaming(me),
+		Source:    location(me),
+		Rule:      r,
 	}, true
 }
 
+// methodRule returns the merged rules in scope for the method
+// fields set on method rules override service rules, which override file rules
+func methodRule(m pgs.Method) (*grappapb.Rule, bool) {
+	var res *grappapb.Rule
+	for _, o := range []struct {
+		opts proto.Message
+		xt   protoreflect.ExtensionType
+	}{
+		{opts: m.File().Descriptor().GetOptions(), xt: grappapb.E_FileRule},
+		{opts: m.Service().Descriptor().GetOptions(), xt: grappapb.E_ServiceRule},
+		{opts: m.Descriptor().GetOptions(), xt: grappapb.E_Rule},
+	} {
+		if r, ok := getRule(o.opts, o.xt); ok {
+			res = mergeRule(res, r)
+		}
+	}
+
+	return res, res != nil
+}
+
+// mergeRule returns a copy of the wider rule, with each field that is set on the
+// narrower rule replacing the wider value. Repeated fields are replaced rather than
+// appended, and unset fields, including a false allow_anonymous, are inherited.
+func mergeRule(wider, narrower *grappapb.Rule) *grappapb.Rule {
+	if wider == nil {
+		return proto.Clone(narrower).(*grappapb.Rule)
+	}
+
+	res := proto.Clone(wider).(*grappapb.Rule)
+	rm := res.ProtoReflect()
+
+	proto.Clone(narrower).ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		rm.Set(fd, v)
+		return true
+	})
+
+	return res
+}
+
+func getRule(o proto.Message, xt protoreflect.ExtensionType) (*grappapb.Rule, bool) {
+	if !proto.HasExtension(o, xt) {
+		return nil, false
+	}
+
+	return proto.GetExtension(o, xt).(*grappapb.Rule), true
+}
+
+// validateFieldBinding validates the binding field path against the input message
+// intermediate fields must be singular messages, and the bound field must be a
+// string or integer, which can only be repeated for the IN operator
+func validateFieldBinding(msg pgs.Message, b *grappapb.FieldBinding) error {
+	ns := strings.Split(b.GetField(), ".")
+	for i, n := range ns {
+		f := messageField(msg, n)
+		if f == nil {
+			return fmt.Errorf("field %s not found in %s", n, msg.FullyQualifiedName())
+		}
+
+		ft := f.Type()
+		if i < len(ns)-1 {
+			if !ft.IsEmbed() || ft.IsRepeated() || ft.IsMap() {
+				return fmt.Errorf("field %s is not a singular message", n)
+			}
+
+			msg = ft.Embed()
+			continue
+		}
+
+		pt := ft.ProtoType()
+		switch {
+		case ft.IsMap():
+			return fmt.Errorf("map field %s cannot be bound", n)
+		case ft.IsRepeated():
+			if op := b.GetOperator(); op != grappapb.FieldBinding_IN {
+				return fmt.Errorf("%s does not support repeated field %s", op, n)
+			}
+			pt = ft.Element().ProtoType()
+		}
+
+		if pt != pgs.StringT && !pt.IsInt() {
+			return fmt.Errorf("field %s must be a string or integer", n)
+		}
+	}
+
+	return nil
+}
+
+// checkCondition type checks the condition expression against the method input message
+// the message descriptor is resolved from the file and its transitive imports
+func checkCondition(me pgs.Method, expr string) error {
+	fds := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{me.File().Descriptor()},
+	}
+
+	for _, f := range me.File().TransitiveImports() {
+		fds.File = append(fds.File, f.Descriptor())
+	}
+
+	files, err := protodesc.NewFiles(fds)
+	if err != nil {
+		return err
+	}
+
+	d, err := files.FindDescriptorByName(protoreflect.FullName(strings.TrimPrefix(me.Input().FullyQualifiedName(), ".")))
+	if err != nil {
+		return err
+	}
+
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return fmt.Errorf("invalid input type: %s", d.FullName())
+	}
+
+	_, err = condition.Compile(expr, md)
+	return err
+}
+
+func messageField(msg pgs.Message, name string) pgs.Field {
+	for _, f := range msg.Fields() {
+		if f.Name().String() == name {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// ruleWarnings returns warnings for rules that are valid, but unlikely to
+// behave as intended
+func ruleWarnings(r *grappapb.Rule) []string {
+	if !r.GetAllowAnonymous() {
+		return nil
+	}
+
+	const (
+		ignored = "allow_anonymous permits requests without a token regardless of %s"
+		denied  = "allow_anonymous requests without a token are always denied by %s"
+		noClaim = "%s is evaluated without claims for requests without a token"
+	)
+
+	var ws []string
+	for _, f := range []struct {
+		name   string
+		set    bool
+		format string
+	}{
+		{name: "require_scope", set: len(r.GetRequireScope()) > 0, format: ignored},
+		{name: "scope_expression", set: r.GetScopeExpression() != "", format: ignored},
+		{name: "require_claim", set: len(r.GetRequireClaim()) > 0, format: ignored},
+		{name: "require_role", set: len(r.GetRequireRole()) > 0, format: ignored},
+		{name: "bind_field", set: len(r.GetBindField()) > 0, format: denied},
+		{name: "policy", set: r.GetPolicy() != "", format: noClaim},
+		{name: "condition", set: r.GetCondition() != "", format: noClaim},
+	} {
+		if f.set {
+			ws = append(ws, fmt.Sprintf(f.format, f.name))
+		}
+	}
+
+	return ws
+}
+
+func marshalManifest(rs *grappapb.RuleSet, format string) ([]byte, error) {
+	b, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(rs)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == manifestYAML {
+		return yaml.JSONToYAML(b)
+	}
+
+	// protojson output is intentionally unstable, so it is re-indented
+	buf := new(bytes.Buffer)
+	if err = json.Indent(buf, b, "", "  "); err != nil {
+		return nil, err
+	}
+
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func streaming(m pgs.Method) grappapb.MethodRule_Streaming {
+	switch {
+	case m.ClientStreaming() && m.ServerStreaming():
+		return grappapb.MethodRule_BIDI_STREAMING
+	case m.ClientStreaming():
+		return grappapb.MethodRule_CLIENT_STREAMING
+	case m.ServerStreaming():
+		return grappapb.MethodRule_SERVER_STREAMING
+	default:
+		return grappapb.MethodRule_UNARY
+	}
+}
+
+func location(e pgs.Entity) string {
+	p := e.File().InputPath().String()
+
+	sci := e.SourceCodeInfo()
+	if sci == nil {
+		return p
+	}
+
+	if span := sci.Location().GetSpan(); len(span) > 1 {
+		return fmt.Sprintf("%s:%d:%d", p, span[0]+1, span[1]+1)
+	}
+
+	return p
+}
+
 func methodPattern(m pgs.Method) string {
 	e := strings.Split(m.FullyQualifiedName(), ".")
 	return fmt.Sprintf("/%s/%s", strings.Join(e[1:len(e)-1], "."), e[len(e)-1])
@@ -2,6 +2,7 @@ package generator_test
 
 import (
 	"bytes"
+	"io/ioutil"
 	"os"
 	"strings"
 	"testing"
@@ -13,21 +14,7 @@ import (
 )
 
 func TestNew(t *testing.T) {
-	req, err := os.Open("./testdata/code_generator_request.pb.bin")
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer req.Close()
-
-	buf := new(bytes.Buffer)
-	pgs.Init(
-		pgs.ProtocInput(req),
-		pgs.ProtocOutput(buf)).
-		RegisterModule(generator.New()).
-		RegisterPostProcessor(pgsgo.GoFmt()).
-		Render()
-
-	gen := buf.String()
+	gen := render(t, "./testdata/code_generator_request.pb.bin")
 	tests := []struct {
 		name   string
 		assert func(*testing.T, string)
@@ -49,18 +36,122 @@ func TestNew(t *testing.T) {
 			},
 		},
 		{
-			name: "should generate correct register funcs for allow_anonymous",
+			name: "should not import internal packages",
+			assert: func(t *testing.T, gen string) {
+				if strings.Contains(gen, `"github.com/stevecallear/grappa/internal/`) {
+					t.Errorf("got %s, expected no internal package imports", gen)
+				}
+			},
+		},
+		{
+			name: "should not generate manifests by default",
+			assert: func(t *testing.T, gen string) {
+				if strings.Contains(gen, ".grappa.json") || strings.Contains(gen, ".grappa.yaml") {
+					t.Errorf("got %s, expected no manifest files", gen)
+				}
+			},
+		},
+		{
+			name: "should generate correct rules for allow_anonymous",
 			assert: func(t *testing.T, gen string) {
 				if !strings.Contains(gen, allowAnonExp) {
-					t.Errorf("got %s, expected a correct register func for AllowAnonService", gen)
+					t.Errorf("got %s, expected a correct rule for AllowAnonService", gen)
 				}
 			},
 		},
 		{
-			name: "should generate correct register funcs for require_scope",
+			name: "should generate correct rules for require_scope",
 			assert: func(t *testing.T, gen string) {
 				if !strings.Contains(gen, requireScopeExp) {
-					t.Errorf("got %s, expected a correct register func for RequireScopeService", gen)
+					t.Errorf("got %s, expected a correct rule for RequireScopeService", gen)
+				}
+			},
+		},
+		{
+			name: "should generate correct rules for scope_expression",
+			assert: func(t *testing.T, gen string) {
+				if !strings.Contains(gen, scopeExpressionExp) {
+					t.Errorf("got %s, expected a correct rule for ScopeExpressionService", gen)
+				}
+			},
+		},
+		{
+			name: "should generate correct rules for require_claim",
+			assert: func(t *testing.T, gen string) {
+				if !strings.Contains(gen, requireClaimExp) {
+					t.Errorf("got %s, expected a correct rule for RequireClaimService", gen)
+				}
+			},
+		},
+		{
+			name: "should generate correct rules for require_role",
+			assert: func(t *testing.T, gen string) {
+				if !strings.Contains(gen, requireRoleExp) {
+					t.Errorf("got %s, expected a correct rule for RequireRoleService", gen)
+				}
+			},
+		},
+		{
+			name: "should generate correct rules for policy",
+			assert: func(t *testing.T, gen string) {
+				if !strings.Contains(gen, policyExp) {
+					t.Errorf("got %s, expected a correct rule for PolicyService", gen)
+				}
+			},
+		},
+		{
+			name: "should generate correct rules for field bindings",
+			assert: func(t *testing.T, gen string) {
+				if !strings.Contains(gen, fieldBindingExp) {
+					t.Errorf("got %s, expected a correct rule for FieldBindingService", gen)
+				}
+			},
+		},
+		{
+			name: "should generate correct rules for conditions",
+			assert: func(t *testing.T, gen string) {
+				if !strings.Contains(gen, conditionExp) {
+					t.Errorf("got %s, expected a correct rule for ConditionService", gen)
+				}
+			},
+		},
+		{
+			name: "should generate rules maps",
+			assert: func(t *testing.T, gen string) {
+				if !strings.Contains(gen, rulesMapExp) {
+					t.Errorf("got %s, expected a correct rules map for ServiceRuleService", gen)
+				}
+			},
+		},
+		{
+			name: "should generate register funcs",
+			assert: func(t *testing.T, gen string) {
+				if !strings.Contains(gen, registerExp) {
+					t.Errorf("got %s, expected a correct register func for ServiceRuleService", gen)
+				}
+			},
+		},
+		{
+			name: "should generate file rules for methods without a service or method rule",
+			assert: func(t *testing.T, gen string) {
+				if !strings.Contains(gen, fileRuleExp) {
+					t.Errorf("got %s, expected a correct rule for FileRuleService", gen)
+				}
+			},
+		},
+		{
+			name: "should generate service rules for methods without a method rule",
+			assert: func(t *testing.T, gen string) {
+				if !strings.Contains(gen, serviceRuleExp) {
+					t.Errorf("got %s, expected a correct rule for ServiceRuleService", gen)
+				}
+			},
+		},
+		{
+			name: "should merge method rules with service rules",
+			assert: func(t *testing.T, gen string) {
+				if !strings.Contains(gen, mergeRuleExp) {
+					t.Errorf("got %s, expected a merged rule for ServiceRuleService", gen)
 				}
 			},
 		},
@@ -73,25 +164,387 @@ func TestNew(t *testing.T) {
 	}
 }
 
-const (
-	allowAnonExp = `func RegisterAllowAnonServiceServerRules(a grappa.Registry) {
+func TestNew_InvalidRules(t *testing.T) {
+	gen := render(t, "./testdata/invalid/code_generator_request.pb.bin")
+
+	tests := []struct {
+		name string
+		exp  string
+	}{
+		{
+			name: "should return an error for invalid scope expressions",
+			exp:  "internal/generator/testdata/invalid/invalid_rules.proto:10:5: /grappa.test.invalid.InvalidScopeExpressionService/Method: invalid scope expression",
+		},
+		{
+			name: "should return an error for invalid claim requirements",
+			exp:  "/grappa.test.invalid.InvalidClaimRequirementService/Method: invalid claim requirement: EQUALS requires exactly one value",
+		},
+		{
+			name: "should return an error for invalid field bindings",
+			exp:  "/grappa.test.invalid.InvalidFieldBindingService/Method: invalid field binding: EQUALS does not support repeated field group_ids",
+		},
+		{
+			name: "should return an error for invalid conditions",
+			exp:  "internal/generator/testdata/invalid/invalid_rules.proto:50:5: /grappa.test.invalid.InvalidConditionService/Method: invalid condition: 1:8: undefined field 'user_id'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(gen, tt.exp) {
+				t.Errorf("got %s, expected %s", gen, tt.exp)
+			}
+		})
+	}
+}
+
+func TestNew_RequireRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		exp   []string
+		nexp  []string
+	}{
+		{
+			name:  "should return an error for invalid values",
+			value: "maybe",
+			exp:   []string{"invalid require_rules value"},
+		},
+		{
+			name:  "should not return errors for methods without rules by default",
+			value: "false",
+			nexp:  []string{"rule not specified"},
+		},
+		{
+			name:  "should return errors for methods without rules",
+			value: "true",
+			exp: []string{
+				"internal/generator/testdata/with_rules.proto:27:5: /grappa.test.NoRuleService/Method: rule not specified",
+				"internal/generator/testdata/without_rules.proto:9:5: /grappa.test.NoRuleService2/Method: rule not specified",
+			},
+			nexp: []string{"/grappa.test.AllowAnonService/Method: rule not specified"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := render(t, "./testdata/code_generator_request.pb.bin", func(p pgs.Parameters) {
+				p.SetStr("require_rules", tt.value)
+			})
 
-	a.Register("/grappa.test.AllowAnonService/Method", &grappapb.Rule{
-		AllowAnonymous: true,
-		RequireScope:   []string{},
+			for _, exp := range tt.exp {
+				if !strings.Contains(gen, exp) {
+					t.Errorf("got %s, expected %s", gen, exp)
+				}
+			}
+
+			for _, nexp := range tt.nexp {
+				if strings.Contains(gen, nexp) {
+					t.Errorf("got %s, expected no %s", gen, nexp)
+				}
+			}
+		})
+	}
+}
+
+func TestNew_Warnings(t *testing.T) {
+	log := captureStderr(t, func() {
+		render(t, "./testdata/invalid/code_generator_request.pb.bin")
+	})
+
+	tests := []struct {
+		name string
+		exp  string
+	}{
+		{
+			name: "should warn for anonymous rules with scope requirements",
+			exp:  "warning: internal/generator/testdata/invalid/invalid_rules.proto:29:5: /grappa.test.invalid.ContradictoryRuleService/Method: allow_anonymous permits requests without a token regardless of require_scope",
+		},
+		{
+			name: "should warn for anonymous rules with role requirements",
+			exp:  "/grappa.test.invalid.ContradictoryRuleService/Method: allow_anonymous permits requests without a token regardless of require_role",
+		},
+		{
+			name: "should warn for anonymous rules with field bindings",
+			exp:  "/grappa.test.invalid.AnonymousRequestRuleService/Method: allow_anonymous requests without a token are always denied by bind_field",
+		},
+		{
+			name: "should warn for anonymous rules with policies",
+			exp:  "/grappa.test.invalid.AnonymousRequestRuleService/Method: policy is evaluated without claims for requests without a token",
+		},
+		{
+			name: "should warn for anonymous rules with conditions",
+			exp:  "/grappa.test.invalid.AnonymousRequestRuleService/Method: condition is evaluated without claims for requests without a token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(log, tt.exp) {
+				t.Errorf("got %s, expected %s", log, tt.exp)
+			}
+		})
+	}
+
+	t.Run("should not warn for consistent rules", func(t *testing.T) {
+		if strings.Contains(log, "InvalidScopeExpressionService") {
+			t.Errorf("got %s, expected no warning for InvalidScopeExpressionService", log)
+		}
 	})
+}
+
+func TestNew_Manifest(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		exp    []string
+	}{
+		{
+			name:   "should return an error for invalid formats",
+			format: "xml",
+			exp:    []string{"invalid manifest format: xml"},
+		},
+		{
+			name:   "should generate json manifests",
+			format: "json",
+			exp: []string{
+				"testdata/with_rules.pb.grappa.json",
+				jsonManifestExp,
+			},
+		},
+		{
+			name:   "should generate yaml manifests",
+			format: "yaml",
+			exp: []string{
+				"testdata/with_rules.pb.grappa.yaml",
+				yamlManifestExp,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := render(t, "./testdata/code_generator_request.pb.bin", func(p pgs.Parameters) {
+				p.SetStr("manifest", tt.format)
+			})
+
+			for _, exp := range tt.exp {
+				if !strings.Contains(gen, exp) {
+					t.Errorf("got %s, expected %s", gen, exp)
+				}
+			}
+		})
+	}
+}
+
+func render(t *testing.T, path string, params ...pgs.ParamMutator) string {
+	req, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer req.Close()
+
+	buf := new(bytes.Buffer)
+	pgs.Init(
+		pgs.ProtocInput(req),
+		pgs.ProtocOutput(buf),
+		pgs.MutateParams(params...)).
+		RegisterModule(generator.New()).
+		RegisterPostProcessor(pgsgo.GoFmt()).
+		Render()
+
+	return buf.String()
+}
+
+// captureStderr returns the stderr output written by fn
+// the generator logger writes to the os.Stderr value at initialisation
+func captureStderr(t *testing.T, fn func()) string {
+	f, err := ioutil.TempFile(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	stderr := os.Stderr
+	os.Stderr = f
+	defer func() { os.Stderr = stderr }()
+
+	fn()
+
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+const (
+	allowAnonExp = `var AllowAnonService_Method_Rule = &grappapb.Rule{
+	AllowAnonymous: true,
+	RequireScope:   []string{},
+}`
+
+	requireScopeExp = `var RequireScopeService_Method_Rule = &grappapb.Rule{
+	AllowAnonymous: false,
+	RequireScope: []string{
+		"scope_a",
+		"scope_b",
+	},
+}`
+
+	fileRuleExp = `var FileRuleService_Method_Rule = &grappapb.Rule{
+	AllowAnonymous: false,
+	RequireScope: []string{
+		"file_scope",
+	},
+}`
+
+	serviceRuleExp = `var ServiceRuleService_Method_Rule = &grappapb.Rule{
+	AllowAnonymous: false,
+	RequireScope: []string{
+		"service_scope",
+	},
+}
+
+// ServiceRuleService_OverrideMethod_Rule is the rule for /grappa.test.ServiceRuleService/OverrideMethod
+var ServiceRuleService_OverrideMethod_Rule = &grappapb.Rule{
+	AllowAnonymous: false,
+	RequireScope: []string{
+		"method_scope",
+	},
+}`
 
+	mergeRuleExp = `var ServiceRuleService_MergeMethod_Rule = &grappapb.Rule{
+	AllowAnonymous: false,
+	RequireScope: []string{
+		"service_scope",
+	},
+	RequireRole: []string{
+		"admin",
+	},
 }`
 
-	requireScopeExp = `func RegisterRequireScopeServiceServerRules(a grappa.Registry) {
+	scopeExpressionExp = `var ScopeExpressionService_Method_Rule = &grappapb.Rule{
+	AllowAnonymous:  false,
+	RequireScope:    []string{},
+	ScopeExpression: "admin || (orders:read && orders:write)",
+}`
 
-	a.Register("/grappa.test.RequireScopeService/Method", &grappapb.Rule{
-		AllowAnonymous: false,
-		RequireScope: []string{
-			"scope_a",
-			"scope_b",
+	requireClaimExp = `var RequireClaimService_Method_Rule = &grappapb.Rule{
+	AllowAnonymous: false,
+	RequireScope:   []string{},
+	RequireClaim: []*grappapb.ClaimRequirement{
+		{
+			Claim:    "tenant_tier",
+			Operator: grappapb.ClaimRequirement_EQUALS,
+			Values: []string{
+				"premium",
+			},
 		},
-	})
+		{
+			Claim:    "realm_access.roles",
+			Operator: grappapb.ClaimRequirement_IN,
+			Values: []string{
+				"admin",
+				"user",
+			},
+		},
+		{
+			Claim:    "email_verified",
+			Operator: grappapb.ClaimRequirement_EXISTS,
+			Values:   []string{},
+		},
+	},
+}`
+
+	requireRoleExp = `var RequireRoleService_Method_Rule = &grappapb.Rule{
+	AllowAnonymous: false,
+	RequireScope:   []string{},
+	RequireRole: []string{
+		"admin",
+		"support",
+	},
+}`
+
+	policyExp = `var PolicyService_Method_Rule = &grappapb.Rule{
+	AllowAnonymous: false,
+	RequireScope: []string{
+		"accounts:read",
+	},
+	Policy: "account_owner",
+}`
 
+	fieldBindingExp = `var FieldBindingService_Method_Rule = &grappapb.Rule{
+	AllowAnonymous: false,
+	RequireScope:   []string{},
+	BindField: []*grappapb.FieldBinding{
+		{
+			Field:    "user_id",
+			Claim:    "sub",
+			Operator: grappapb.FieldBinding_EQUALS,
+		},
+		{
+			Field:    "account.tenant_id",
+			Claim:    "tenant_id",
+			Operator: grappapb.FieldBinding_EQUALS,
+		},
+		{
+			Field:    "group_ids",
+			Claim:    "groups",
+			Operator: grappapb.FieldBinding_IN,
+		},
+	},
+}`
+
+	conditionExp = `var ConditionService_Method_Rule = &grappapb.Rule{
+	AllowAnonymous: false,
+	RequireScope:   []string{},
+	Condition:      "request.user_id == claims.sub || 'admin' in claims.roles",
+}`
+
+	rulesMapExp = `// ServiceRuleServiceRules contains the ServiceRuleService rules keyed by full method name
+var ServiceRuleServiceRules = map[string]*grappapb.Rule{
+	"/grappa.test.ServiceRuleService/Method":         ServiceRuleService_Method_Rule,
+	"/grappa.test.ServiceRuleService/OverrideMethod": ServiceRuleService_OverrideMethod_Rule,
+	"/grappa.test.ServiceRuleService/MergeMethod":    ServiceRuleService_MergeMethod_Rule,
 }`
+
+	registerExp = `func RegisterServiceRuleServiceServerRules(a grappa.Registry) {
+	a.Register("/grappa.test.ServiceRuleService/Method", proto.Clone(ServiceRuleService_Method_Rule).(*grappapb.Rule))
+	a.Register("/grappa.test.ServiceRuleService/OverrideMethod", proto.Clone(ServiceRuleService_OverrideMethod_Rule).(*grappapb.Rule))
+	a.Register("/grappa.test.ServiceRuleService/MergeMethod", proto.Clone(ServiceRuleService_MergeMethod_Rule).(*grappapb.Rule))
+}`
+
+	jsonManifestExp = `    {
+      "full_method": "/grappa.test.StreamingService/BidiStream",
+      "streaming": "BIDI_STREAMING",
+      "rule": {
+        "allow_anonymous": false,
+        "require_scope": [
+          "stream"
+        ],
+        "scope_expression": "",
+        "require_claim": [],
+        "require_role": [],
+        "policy": "",
+        "bind_field": [],
+        "condition": ""
+      },
+      "source": "internal/generator/testdata/with_rules.proto:75:5"
+    }`
+
+	yamlManifestExp = `- full_method: /grappa.test.StreamingService/ServerStream
+  rule:
+    allow_anonymous: false
+    bind_field: []
+    condition: ""
+    policy: ""
+    require_claim: []
+    require_role: []
+    require_scope:
+    - stream
+    scope_expression: ""
+  source: internal/generator/testdata/with_rules.proto:73:5
+  streaming: SERVER_STREAMING`
 )
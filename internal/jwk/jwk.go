@@ -0,0 +1,155 @@
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+type (
+	// Key represents a parsed json web key
+	Key struct {
+		ID        string
+		Algorithm string
+		Key       interface{}
+	}
+
+	set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	jwk struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Alg string `json:"alg"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		Crv string `json:"crv"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+		K   string `json:"k"`
+	}
+)
+
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+// Parse parses the json web key set, ignoring encryption and unsupported keys
+// keys with an unsupported type or curve are skipped, while malformed keys are errors
+func Parse(b []byte) ([]Key, error) {
+	var s set
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+
+	ks := make([]Key, 0, len(s.Keys))
+	for _, k := range s.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		pk, err := k.parse()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s: %w", k.Kid, err)
+		}
+		if pk == nil {
+			continue
+		}
+
+		ks = append(ks, Key{
+			ID:        k.Kid,
+			Algorithm: k.Alg,
+			Key:       pk,
+		})
+	}
+
+	return ks, nil
+}
+
+func (k jwk) parse() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		c, ok := curves[k.Crv]
+		if !ok {
+			return nil, nil
+		}
+
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		if !c.IsOnCurve(x, y) {
+			return nil, errors.New("invalid curve point")
+		}
+
+		return &ecdsa.PublicKey{Curve: c, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, nil
+		}
+
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid key size")
+		}
+
+		return ed25519.PublicKey(x), nil
+
+	case "oct":
+		return decode(k.K)
+
+	default:
+		return nil, nil
+	}
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := decode(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
+
+func decode(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("missing key parameter")
+	}
+
+	return base64.RawURLEncoding.DecodeString(s)
+}
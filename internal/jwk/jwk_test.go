@@ -0,0 +1,116 @@
+package jwk_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/stevecallear/grappa/internal/jwk"
+)
+
+func TestParse(t *testing.T) {
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaJWK := fmt.Sprintf(`{"kty":"RSA","kid":"rsa","alg":"RS256","use":"sig","n":"%s","e":"%s"}`,
+		encode(rk.N.Bytes()), encode(big.NewInt(int64(rk.E)).Bytes()))
+
+	ecJWK := fmt.Sprintf(`{"kty":"EC","kid":"ec","crv":"P-256","x":"%s","y":"%s"}`,
+		encode(ek.X.Bytes()), encode(ek.Y.Bytes()))
+
+	edk, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	okpJWK := fmt.Sprintf(`{"kty":"OKP","kid":"okp","crv":"Ed25519","x":"%s"}`, encode(edk))
+
+	octJWK := fmt.Sprintf(`{"kty":"oct","kid":"oct","k":"%s"}`, encode([]byte("secret")))
+
+	tests := []struct {
+		name  string
+		input string
+		exp   []jwk.Key
+		err   bool
+	}{
+		{
+			name:  "should return an error if the json is invalid",
+			input: `{`,
+			err:   true,
+		},
+		{
+			name:  "should return an error if a key is invalid",
+			input: `{"keys":[{"kty":"RSA","kid":"rsa"}]}`,
+			err:   true,
+		},
+		{
+			name:  "should return an error if the point is not on the curve",
+			input: `{"keys":[{"kty":"EC","kid":"ec","crv":"P-256","x":"AQ","y":"AQ"}]}`,
+			err:   true,
+		},
+		{
+			name:  "should return an error if the okp key size is invalid",
+			input: `{"keys":[{"kty":"OKP","kid":"okp","crv":"Ed25519","x":"AA"}]}`,
+			err:   true,
+		},
+		{
+			name:  "should ignore encryption and unsupported keys",
+			input: fmt.Sprintf(`{"keys":[{"kty":"oct","use":"enc","k":"AA"},{"kty":"unknown"},%s]}`, octJWK),
+			exp: []jwk.Key{
+				{ID: "oct", Key: []byte("secret")},
+			},
+		},
+		{
+			name: "should ignore keys with unsupported curves",
+			input: fmt.Sprintf(`{"keys":[{"kty":"EC","kid":"ec","crv":"P-224","x":"AA","y":"AA"},{"kty":"OKP","kid":"okp","crv":"X25519","x":"AA"},%s]}`,
+				octJWK),
+			exp: []jwk.Key{
+				{ID: "oct", Key: []byte("secret")},
+			},
+		},
+		{
+			name:  "should parse rsa, ec, okp and symmetric keys",
+			input: fmt.Sprintf(`{"keys":[%s,%s,%s,%s]}`, rsaJWK, ecJWK, okpJWK, octJWK),
+			exp: []jwk.Key{
+				{ID: "rsa", Algorithm: "RS256", Key: &rk.PublicKey},
+				{ID: "ec", Key: &ek.PublicKey},
+				{ID: "okp", Key: edk},
+				{ID: "oct", Key: []byte("secret")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act, err := jwk.Parse([]byte(tt.input))
+			if err != nil && !tt.err {
+				t.Errorf("got %v, expected nil", err)
+			}
+			if err == nil && tt.err {
+				t.Error("got nil, expected an error")
+			}
+
+			if err == nil && !reflect.DeepEqual(act, tt.exp) {
+				t.Errorf("got %v, expected %v", act, tt.exp)
+			}
+		})
+	}
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,209 @@
+package scope
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type (
+	// Expr represents a parsed scope expression
+	Expr interface {
+		// Eval evaluates the expression using the specified scope func
+		Eval(has func(scope string) bool) bool
+	}
+
+	scopeExpr string
+
+	notExpr struct {
+		expr Expr
+	}
+
+	binaryExpr struct {
+		and         bool
+		left, right Expr
+	}
+
+	parser struct {
+		tokens []string
+		pos    int
+	}
+)
+
+// Parse parses the scope expression, e.g. "admin || (orders:read && orders:write)"
+// the && operator takes precedence over ||, and ! negates the following operand
+func Parse(s string) (Expr, error) {
+	ts, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ts) < 1 {
+		return nil, errors.New("empty scope expression")
+	}
+
+	p := &parser{tokens: ts}
+
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t, ok := p.peek(); ok {
+		return nil, fmt.Errorf("unexpected token: %s", t)
+	}
+
+	return e, nil
+}
+
+// Scopes returns the scopes that are not negated by the expression, in order of appearance
+// a set containing each of them satisfies the expression, unless it requires a negated scope
+func Scopes(e Expr) []string {
+	var ss []string
+	var walk func(Expr, bool)
+	walk = func(e Expr, negated bool) {
+		switch te := e.(type) {
+		case scopeExpr:
+			if !negated {
+				ss = append(ss, string(te))
+			}
+		case notExpr:
+			walk(te.expr, !negated)
+		case binaryExpr:
+			walk(te.left, negated)
+			walk(te.right, negated)
+		}
+	}
+
+	walk(e, false)
+	return ss
+}
+
+func (e scopeExpr) Eval(has func(string) bool) bool {
+	return has(string(e))
+}
+
+func (e notExpr) Eval(has func(string) bool) bool {
+	return !e.expr.Eval(has)
+}
+
+func (e binaryExpr) Eval(has func(string) bool) bool {
+	if e.and {
+		return e.left.Eval(has) && e.right.Eval(has)
+	}
+
+	return e.left.Eval(has) || e.right.Eval(has)
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	return p.parseBinary("||", p.parseAnd)
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	return p.parseBinary("&&", p.parseUnary)
+}
+
+func (p *parser) parseBinary(op string, next func() (Expr, error)) (Expr, error) {
+	l, err := next()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if t, ok := p.peek(); !ok || t != op {
+			return l, nil
+		}
+		p.pos++
+
+		r, err := next()
+		if err != nil {
+			return nil, err
+		}
+
+		l = binaryExpr{and: op == "&&", left: l, right: r}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	t, ok := p.next()
+	if !ok {
+		return nil, errors.New("unexpected end of expression")
+	}
+
+	switch t {
+	case "!":
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr: e}, nil
+
+	case "(":
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if t, ok := p.next(); !ok || t != ")" {
+			return nil, errors.New("missing closing parenthesis")
+		}
+		return e, nil
+
+	case ")", "&&", "||":
+		return nil, fmt.Errorf("unexpected token: %s", t)
+
+	default:
+		return scopeExpr(t), nil
+	}
+}
+
+func (p *parser) peek() (string, bool) {
+	if p.pos >= len(p.tokens) {
+		return "", false
+	}
+
+	return p.tokens[p.pos], true
+}
+
+func (p *parser) next() (string, bool) {
+	t, ok := p.peek()
+	if ok {
+		p.pos++
+	}
+
+	return t, ok
+}
+
+func tokenize(s string) ([]string, error) {
+	var ts []string
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case unicode.IsSpace(rune(c)):
+			i++
+
+		case c == '(' || c == ')' || c == '!':
+			ts = append(ts, string(c))
+			i++
+
+		case c == '&' || c == '|':
+			if i+1 >= len(s) || s[i+1] != c {
+				return nil, fmt.Errorf("invalid operator at position %d", i)
+			}
+			ts = append(ts, s[i:i+2])
+			i += 2
+
+		default:
+			j := strings.IndexFunc(s[i:], func(r rune) bool {
+				return unicode.IsSpace(r) || strings.ContainsRune("()!&|", r)
+			})
+			if j < 0 {
+				j = len(s) - i
+			}
+			ts = append(ts, s[i:i+j])
+			i += j
+		}
+	}
+
+	return ts, nil
+}
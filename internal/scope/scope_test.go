@@ -0,0 +1,156 @@
+package scope_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stevecallear/grappa/internal/scope"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		scopes []string
+		exp    bool
+		err    bool
+	}{
+		{
+			name:  "should return an error if the expression is empty",
+			input: " ",
+			err:   true,
+		},
+		{
+			name:  "should return an error if an operator is invalid",
+			input: "a & b",
+			err:   true,
+		},
+		{
+			name:  "should return an error if an operand is missing",
+			input: "a &&",
+			err:   true,
+		},
+		{
+			name:  "should return an error if a parenthesis is not closed",
+			input: "(a || b",
+			err:   true,
+		},
+		{
+			name:  "should return an error if a parenthesis is not opened",
+			input: "a || b)",
+			err:   true,
+		},
+		{
+			name:  "should return an error if operands are not separated by an operator",
+			input: "a b",
+			err:   true,
+		},
+		{
+			name:   "should evaluate single scopes",
+			input:  "orders:read",
+			scopes: []string{"orders:read"},
+			exp:    true,
+		},
+		{
+			name:   "should evaluate and expressions",
+			input:  "orders:read && orders:write",
+			scopes: []string{"orders:read"},
+			exp:    false,
+		},
+		{
+			name:   "should evaluate or expressions",
+			input:  "orders:read||orders:write",
+			scopes: []string{"orders:write"},
+			exp:    true,
+		},
+		{
+			name:   "should evaluate not expressions",
+			input:  "orders:read && !guest",
+			scopes: []string{"orders:read", "guest"},
+			exp:    false,
+		},
+		{
+			name:   "should give and precedence over or",
+			input:  "admin || orders:read && orders:write",
+			scopes: []string{"admin"},
+			exp:    true,
+		},
+		{
+			name:   "should evaluate parenthesised expressions",
+			input:  "(admin || orders:read) && orders:write",
+			scopes: []string{"admin"},
+			exp:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := scope.Parse(tt.input)
+			if err != nil && !tt.err {
+				t.Errorf("got %v, expected nil", err)
+			}
+			if err == nil && tt.err {
+				t.Error("got nil, expected an error")
+			}
+			if err != nil {
+				return
+			}
+
+			act := e.Eval(func(s string) bool {
+				for _, ts := range tt.scopes {
+					if ts == s {
+						return true
+					}
+				}
+				return false
+			})
+
+			if act != tt.exp {
+				t.Errorf("got %v, expected %v", act, tt.exp)
+			}
+		})
+	}
+}
+
+func TestScopes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		exp   []string
+	}{
+		{
+			name:  "should return single scopes",
+			input: "orders:read",
+			exp:   []string{"orders:read"},
+		},
+		{
+			name:  "should return the operands of binary expressions",
+			input: "admin || (orders:read && orders:write)",
+			exp:   []string{"admin", "orders:read", "orders:write"},
+		},
+		{
+			name:  "should not return negated scopes",
+			input: "orders:read && !orders:write",
+			exp:   []string{"orders:read"},
+		},
+		{
+			name:  "should return scopes that are negated twice",
+			input: "!(admin || !orders:read)",
+			exp:   []string{"orders:read"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := scope.Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			act := scope.Scopes(e)
+			if !reflect.DeepEqual(act, tt.exp) {
+				t.Errorf("got %v, expected %v", act, tt.exp)
+			}
+		})
+	}
+}
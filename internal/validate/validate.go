@@ -0,0 +1,105 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/stevecallear/grappa/internal/scope"
+	"github.com/stevecallear/grappa/proto/grappapb"
+)
+
+// Pattern validates the method pattern
+func Pattern(p string) error {
+	if p == "" {
+		return errors.New("empty pattern")
+	}
+
+	if i := strings.Index(p, "*"); i >= 0 && i != len(p)-1 {
+		return fmt.Errorf("invalid pattern %s: wildcards must be trailing", p)
+	}
+
+	if p != "*" && !strings.HasPrefix(p, "/") {
+		return fmt.Errorf("invalid pattern %s: patterns must start with /", p)
+	}
+
+	return nil
+}
+
+// Rule validates the rule
+func Rule(r *grappapb.Rule) error {
+	if r == nil {
+		return errors.New("rule not specified")
+	}
+
+	if e := r.GetScopeExpression(); e != "" {
+		if _, err := scope.Parse(e); err != nil {
+			return fmt.Errorf("invalid scope expression: %v", err)
+		}
+	}
+
+	for _, cr := range r.GetRequireClaim() {
+		if err := claimRequirement(cr); err != nil {
+			return fmt.Errorf("invalid claim requirement: %v", err)
+		}
+	}
+
+	for _, b := range r.GetBindField() {
+		if err := FieldBinding(b); err != nil {
+			return fmt.Errorf("invalid field binding: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func claimRequirement(cr *grappapb.ClaimRequirement) error {
+	if cr.GetClaim() == "" {
+		return errors.New("claim not specified")
+	}
+
+	n := len(cr.GetValues())
+	switch op := cr.GetOperator(); op {
+	case grappapb.ClaimRequirement_EQUALS:
+		if n != 1 {
+			return fmt.Errorf("%s requires exactly one value", op)
+		}
+	case grappapb.ClaimRequirement_IN, grappapb.ClaimRequirement_PREFIX:
+		if n < 1 {
+			return fmt.Errorf("%s requires at least one value", op)
+		}
+	case grappapb.ClaimRequirement_EXISTS:
+		if n > 0 {
+			return fmt.Errorf("%s does not accept values", op)
+		}
+	default:
+		return fmt.Errorf("unknown operator: %s", op)
+	}
+
+	return nil
+}
+
+// FieldBinding validates the field binding
+func FieldBinding(b *grappapb.FieldBinding) error {
+	if b.GetField() == "" {
+		return errors.New("field not specified")
+	}
+
+	for _, n := range strings.Split(b.GetField(), ".") {
+		if n == "" {
+			return fmt.Errorf("invalid field path: %s", b.GetField())
+		}
+	}
+
+	if b.GetClaim() == "" {
+		return errors.New("claim not specified")
+	}
+
+	switch op := b.GetOperator(); op {
+	case grappapb.FieldBinding_EQUALS, grappapb.FieldBinding_IN:
+	default:
+		return fmt.Errorf("unknown operator: %s", op)
+	}
+
+	return nil
+}
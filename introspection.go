@@ -0,0 +1,200 @@
+package grappa
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+type (
+	// IntrospectionOptions represents a set of token introspection options
+	IntrospectionOptions struct {
+		Client           *http.Client
+		EndpointParams   url.Values
+		CacheTTL         time.Duration
+		NegativeCacheTTL time.Duration
+	}
+
+	introspector struct {
+		endpointURL  string
+		clientID     string
+		clientSecret string
+		opts         IntrospectionOptions
+		mu           sync.Mutex
+		cache        map[string]introspection
+		swept        time.Time
+	}
+
+	introspection struct {
+		claims jwt.MapClaims
+		expiry time.Time
+	}
+)
+
+var defaultIntrospectionOptions = IntrospectionOptions{
+	Client:           http.DefaultClient,
+	CacheTTL:         5 * time.Minute,
+	NegativeCacheTTL: time.Minute,
+}
+
+var errInactiveToken = errors.New("inactive token")
+
+// Introspection configures the authorizor to verify opaque tokens using the
+// specified oauth2 token introspection endpoint (RFC 7662) rather than parsing
+// them as jwts. The introspection response is used as the token claims.
+// Active responses are cached until the token expires, limited to the cache ttl,
+// and inactive responses are cached for the negative cache ttl.
+func Introspection(endpointURL, clientID, clientSecret string, optFns ...func(*IntrospectionOptions)) func(*Options) {
+	i := &introspector{
+		endpointURL:  endpointURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		opts:         defaultIntrospectionOptions,
+		cache:        map[string]introspection{},
+	}
+
+	for _, fn := range optFns {
+		fn(&i.opts)
+	}
+
+	return func(o *Options) {
+		o.IntrospectFn = func(ctx context.Context, _ Context, token string) (jwt.MapClaims, error) {
+			return i.introspect(ctx, token)
+		}
+	}
+}
+
+func (i *introspector) introspect(ctx context.Context, token string) (jwt.MapClaims, error) {
+	key := cacheKey(token)
+	now := time.Now()
+
+	if in, ok := i.get(key, now); ok {
+		if in.claims == nil {
+			return nil, errInactiveToken
+		}
+		return copyClaims(in.claims), nil
+	}
+
+	c, err := i.fetch(ctx, token)
+	if err == errInactiveToken {
+		i.set(key, introspection{expiry: now.Add(i.opts.NegativeCacheTTL)}, now)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	exp := now.Add(i.opts.CacheTTL)
+	if v, ok := c["exp"].(float64); ok {
+		if te := time.Unix(int64(v), 0); te.Before(exp) {
+			exp = te
+		}
+	}
+
+	i.set(key, introspection{claims: c, expiry: exp}, now)
+	return copyClaims(c), nil
+}
+
+func (i *introspector) fetch(ctx context.Context, token string) (jwt.MapClaims, error) {
+	v := url.Values{}
+	for k, vs := range i.opts.EndpointParams {
+		v[k] = vs
+	}
+
+	v.Set("token", token)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.endpointURL, strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if i.clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(i.clientID), url.QueryEscape(i.clientSecret))
+	}
+
+	res, err := i.opts.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected introspection status: %d", res.StatusCode)
+	}
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	c := jwt.MapClaims{}
+	if err = json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+
+	if active, _ := c["active"].(bool); !active {
+		return nil, errInactiveToken
+	}
+
+	delete(c, "active")
+	return c, nil
+}
+
+func (i *introspector) get(key string, now time.Time) (introspection, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	in, ok := i.cache[key]
+	if !ok || !now.Before(in.expiry) {
+		return introspection{}, false
+	}
+
+	return in, true
+}
+
+func (i *introspector) set(key string, in introspection, now time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	// expired entries are removed periodically to bound the cache size
+	if now.Sub(i.swept) > i.opts.CacheTTL {
+		for k, v := range i.cache {
+			if !now.Before(v.expiry) {
+				delete(i.cache, k)
+			}
+		}
+		i.swept = now
+	}
+
+	if now.Before(in.expiry) {
+		i.cache[key] = in
+	}
+}
+
+func copyClaims(c jwt.MapClaims) jwt.MapClaims {
+	cc := make(jwt.MapClaims, len(c))
+	for k, v := range c {
+		cc[k] = v
+	}
+
+	return cc
+}
+
+// cacheKey returns a hash of the token to avoid retaining raw tokens
+func cacheKey(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
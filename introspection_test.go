@@ -0,0 +1,273 @@
+package grappa_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/stevecallear/grappa"
+	"github.com/stevecallear/grappa/proto/grappapb"
+)
+
+type testIntrospector struct {
+	tokens   map[string]map[string]interface{}
+	status   int
+	mu       sync.Mutex
+	requests int
+}
+
+func (i *testIntrospector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.requests++
+
+	if i.status != 0 {
+		w.WriteHeader(i.status)
+		return
+	}
+
+	if id, secret, ok := r.BasicAuth(); !ok || id != "client" || secret != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	res, ok := i.tokens[r.FormValue("token")]
+	if !ok {
+		res = map[string]interface{}{"active": false}
+	}
+
+	json.NewEncoder(w).Encode(res)
+}
+
+func newTestIntrospector() *testIntrospector {
+	return &testIntrospector{
+		tokens: map[string]map[string]interface{}{
+			"active": {
+				"active": true,
+				"sub":    "subject",
+				"scope":  "read write",
+				"exp":    time.Now().Add(time.Hour).Unix(),
+			},
+			"expired": {
+				"active": true,
+				"sub":    "subject",
+				"scope":  "read write",
+				"exp":    time.Now().Add(-time.Hour).Unix(),
+			},
+		},
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		status int
+		token  string
+		exp    jwt.MapClaims
+		err    bool
+	}{
+		{
+			name:   "should return an error if the endpoint fails",
+			secret: "secret",
+			status: http.StatusInternalServerError,
+			token:  "active",
+			err:    true,
+		},
+		{
+			name:   "should return an error if the client credentials are invalid",
+			secret: "invalid",
+			token:  "active",
+			err:    true,
+		},
+		{
+			name:   "should return an error if the token is inactive",
+			secret: "secret",
+			token:  "unknown",
+			err:    true,
+		},
+		{
+			name:   "should return the introspection claims",
+			secret: "secret",
+			token:  "active",
+			exp: jwt.MapClaims{
+				"sub":   "subject",
+				"scope": "read write",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ti := newTestIntrospector()
+			ti.status = tt.status
+
+			svr := httptest.NewServer(ti)
+			defer svr.Close()
+
+			opt := grappa.Options{}
+			grappa.Introspection(svr.URL, "client", tt.secret, func(o *grappa.IntrospectionOptions) {
+				o.Client = svr.Client()
+			})(&opt)
+
+			act, err := opt.IntrospectFn(context.Background(), grappa.Context{}, tt.token)
+			assertErrorExists(t, err, tt.err)
+
+			if act != nil {
+				delete(act, "exp")
+			}
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestIntrospection_Cache(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		token  string
+		ttl    time.Duration
+		exp    int
+	}{
+		{
+			name:  "should cache active responses",
+			token: "active",
+			ttl:   time.Hour,
+			exp:   1,
+		},
+		{
+			name:  "should cache inactive responses",
+			token: "unknown",
+			ttl:   time.Hour,
+			exp:   1,
+		},
+		{
+			name:  "should not cache responses after the cache ttl",
+			token: "active",
+			exp:   2,
+		},
+		{
+			name:  "should not cache responses after the token expiry",
+			token: "expired",
+			ttl:   time.Hour,
+			exp:   2,
+		},
+		{
+			name:   "should not cache errors",
+			status: http.StatusInternalServerError,
+			token:  "active",
+			ttl:    time.Hour,
+			exp:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ti := newTestIntrospector()
+			ti.status = tt.status
+
+			svr := httptest.NewServer(ti)
+			defer svr.Close()
+
+			opt := grappa.Options{}
+			grappa.Introspection(svr.URL, "client", "secret", func(o *grappa.IntrospectionOptions) {
+				o.Client = svr.Client()
+				o.CacheTTL = tt.ttl
+				o.NegativeCacheTTL = tt.ttl
+			})(&opt)
+
+			for i := 0; i < 2; i++ {
+				opt.IntrospectFn(context.Background(), grappa.Context{}, tt.token)
+			}
+
+			assertDeepEqual(t, ti.requests, tt.exp)
+		})
+	}
+}
+
+func TestIntrospection_UnaryInterceptor(t *testing.T) {
+	type customClaims struct {
+		jwt.StandardClaims
+		Scope string `json:"scope"`
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		scope  string
+		assert func(*testing.T, context.Context)
+		err    bool
+	}{
+		{
+			name:  "should return an error if the token is inactive",
+			token: "unknown",
+			scope: "read",
+			err:   true,
+		},
+		{
+			name:  "should return an error if the token has expired",
+			token: "expired",
+			scope: "read",
+			err:   true,
+		},
+		{
+			name:  "should return an error if the scope is invalid",
+			token: "active",
+			scope: "admin",
+			err:   true,
+		},
+		{
+			name:  "should authorize active tokens",
+			token: "active",
+			scope: "read",
+			assert: func(t *testing.T, ctx context.Context) {
+				sub, _ := grappa.SubjectFromContext(ctx)
+				assertDeepEqual(t, sub, "subject")
+
+				md, _ := metadata.FromIncomingContext(ctx)
+				assertDeepEqual(t, md.Get("auth.sub"), []string{"subject"})
+
+				cc, _ := grappa.CustomClaimsFromContext(ctx)
+				assertDeepEqual(t, cc.(*customClaims).Scope, "read write")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svr := httptest.NewServer(newTestIntrospector())
+			defer svr.Close()
+
+			sut := grappa.New(
+				grappa.Introspection(svr.URL, "client", "secret", func(o *grappa.IntrospectionOptions) {
+					o.Client = svr.Client()
+				}),
+				grappa.CaptureClaim("sub", "auth.sub"),
+				grappa.CustomClaims(func() jwt.Claims { return new(customClaims) }),
+				func(o *grappa.Options) {
+					o.ClaimsVerifiers = append(o.ClaimsVerifiers, grappa.VerifyScope())
+				})
+
+			sut.Register("/package.Service/Method", &grappapb.Rule{RequireScope: []string{tt.scope}})
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+tt.token))
+			info := &grpc.UnaryServerInfo{FullMethod: "/package.Service/Method"}
+
+			_, err := sut.UnaryInterceptor(ctx, nil, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+				tt.assert(t, ctx)
+				return nil, nil
+			})
+
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
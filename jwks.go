@@ -0,0 +1,227 @@
+package grappa
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+
+	"github.com/stevecallear/grappa/internal/jwk"
+)
+
+type (
+	// JWKSource represents a json web key set source
+	JWKSource func() ([]byte, error)
+
+	// JWKSOptions represents a set of json web key set options
+	JWKSOptions struct {
+		RefreshInterval time.Duration
+		MaxAge          time.Duration
+	}
+
+	keySet struct {
+		src     JWKSource
+		opts    JWKSOptions
+		mu      sync.RWMutex
+		fetchMu sync.Mutex
+		keys    []jwk.Key
+		fetched time.Time
+		loaded  time.Time
+	}
+)
+
+var (
+	defaultJWKSOptions = JWKSOptions{
+		RefreshInterval: time.Minute,
+		MaxAge:          time.Hour,
+	}
+
+	defaultJWKSClient = &http.Client{
+		Timeout: 10 * time.Second,
+	}
+)
+
+// JWKS configures the authorizor to select the verification key by id from
+// the specified json web key set. The set is refreshed when an unknown key id
+// is encountered, at most once per refresh interval. The set is also refreshed
+// once it is older than the max age, and expired keys are not used if the refresh fails.
+func JWKS(src JWKSource, optFns ...func(*JWKSOptions)) func(*Options) {
+	ks := &keySet{
+		src:  src,
+		opts: defaultJWKSOptions,
+	}
+
+	for _, fn := range optFns {
+		fn(&ks.opts)
+	}
+
+	return func(o *Options) {
+		o.KeyFn = func(_ Context, t *jwt.Token) (interface{}, error) {
+			return ks.key(t)
+		}
+	}
+}
+
+// JWKSFile returns a json web key set source that reads the specified file
+func JWKSFile(path string) JWKSource {
+	return func() ([]byte, error) {
+		return ioutil.ReadFile(path)
+	}
+}
+
+// JWKSReader returns a json web key set source that reads the specified reader
+// once and returns the same set on each subsequent fetch
+func JWKSReader(r io.Reader) JWKSource {
+	var (
+		once sync.Once
+		b    []byte
+		err  error
+	)
+
+	return func() ([]byte, error) {
+		once.Do(func() {
+			b, err = ioutil.ReadAll(r)
+		})
+		return b, err
+	}
+}
+
+// JWKSURL returns a json web key set source that fetches the set from the specified
+// url. If the client is nil, then a default client with a 10 second timeout is used.
+func JWKSURL(url string, c *http.Client) JWKSource {
+	if c == nil {
+		c = defaultJWKSClient
+	}
+
+	return func() ([]byte, error) {
+		res, err := c.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected jwks status: %d", res.StatusCode)
+		}
+
+		return ioutil.ReadAll(res.Body)
+	}
+}
+
+func (s *keySet) key(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	keys, ok := s.current()
+	if !ok {
+		if err := s.refresh(); err != nil {
+			return nil, err
+		}
+
+		if keys, ok = s.current(); !ok {
+			return nil, errors.New("key set expired")
+		}
+	}
+
+	k, ok := find(keys, t, kid)
+	if !ok {
+		if err := s.refresh(); err != nil {
+			return nil, err
+		}
+
+		keys, _ = s.current()
+		if k, ok = find(keys, t, kid); !ok {
+			return nil, fmt.Errorf("key not found: %s", kid)
+		}
+	}
+
+	if k.Algorithm != "" && k.Algorithm != t.Method.Alg() {
+		return nil, fmt.Errorf("invalid signing method: %s", t.Header["alg"])
+	}
+
+	if err := verifyMethod(t, k.Key); err != nil {
+		return nil, err
+	}
+
+	return k.Key, nil
+}
+
+// current returns the cached keys, and false if they are older than the max age
+// the slice is replaced rather than modified on refresh, so it can be read without the lock
+func (s *keySet) current() ([]jwk.Key, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.opts.MaxAge > 0 && time.Since(s.loaded) >= s.opts.MaxAge {
+		return nil, false
+	}
+
+	return s.keys, true
+}
+
+func find(keys []jwk.Key, t *jwt.Token, kid string) (jwk.Key, bool) {
+	if kid != "" {
+		for _, k := range keys {
+			if k.ID == kid {
+				return k, true
+			}
+		}
+		return jwk.Key{}, false
+	}
+
+	var ks []jwk.Key
+	for _, k := range keys {
+		if verifyMethod(t, k.Key) == nil {
+			ks = append(ks, k)
+		}
+	}
+
+	if len(ks) != 1 {
+		return jwk.Key{}, false
+	}
+
+	return ks[0], true
+}
+
+// refresh fetches the key set, at most once per refresh interval
+// concurrent callers wait for a single fetch, while lookups of cached keys
+// continue without blocking
+func (s *keySet) refresh() error {
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+
+	s.mu.RLock()
+	fetched := s.fetched
+	s.mu.RUnlock()
+
+	if !fetched.IsZero() && time.Since(fetched) < s.opts.RefreshInterval {
+		return nil
+	}
+
+	ks, err := s.fetch()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fetched = time.Now()
+	if err != nil {
+		return err
+	}
+
+	s.keys = ks
+	s.loaded = s.fetched
+	return nil
+}
+
+func (s *keySet) fetch() ([]jwk.Key, error) {
+	b, err := s.src()
+	if err != nil {
+		return nil, err
+	}
+
+	return jwk.Parse(b)
+}
@@ -0,0 +1,404 @@
+package grappa_test
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+
+	"github.com/stevecallear/grappa"
+)
+
+func TestJWKS(t *testing.T) {
+	rk, err := jwt.ParseRSAPublicKeyFromPEM([]byte(rsaPublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := newJWKS(
+		rsaJWK("rsa", "RS256", rk),
+		fmt.Sprintf(`{"kty":"oct","kid":"oct","k":"%s"}`, encodeJWK([]byte("secret"))))
+
+	tests := []struct {
+		name   string
+		src    grappa.JWKSource
+		method jwt.SigningMethod
+		kid    string
+		exp    interface{}
+		err    bool
+	}{
+		{
+			name: "should return an error if the source fails",
+			src: func() ([]byte, error) {
+				return nil, os.ErrNotExist
+			},
+			method: jwt.SigningMethodRS256,
+			kid:    "rsa",
+			err:    true,
+		},
+		{
+			name:   "should return an error if the key id is unknown",
+			src:    grappa.JWKSReader(bytes.NewReader(set)),
+			method: jwt.SigningMethodRS256,
+			kid:    "unknown",
+			err:    true,
+		},
+		{
+			name:   "should return an error if the key algorithm does not match",
+			src:    grappa.JWKSReader(bytes.NewReader(set)),
+			method: jwt.SigningMethodPS256,
+			kid:    "rsa",
+			err:    true,
+		},
+		{
+			name:   "should return an error if the key type does not match",
+			src:    grappa.JWKSReader(bytes.NewReader(set)),
+			method: jwt.SigningMethodRS256,
+			kid:    "oct",
+			err:    true,
+		},
+		{
+			name:   "should return the rsa key for the key id",
+			src:    grappa.JWKSReader(bytes.NewReader(set)),
+			method: jwt.SigningMethodRS256,
+			kid:    "rsa",
+			exp:    rk,
+		},
+		{
+			name:   "should return the symmetric key for the key id",
+			src:    grappa.JWKSReader(bytes.NewReader(set)),
+			method: jwt.SigningMethodHS256,
+			kid:    "oct",
+			exp:    []byte("secret"),
+		},
+		{
+			name:   "should return the only matching key if the key id is not set",
+			src:    grappa.JWKSReader(bytes.NewReader(set)),
+			method: jwt.SigningMethodHS512,
+			exp:    []byte("secret"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := grappa.Options{}
+			grappa.JWKS(tt.src)(&opt)
+
+			tok := jwt.New(tt.method)
+			if tt.kid != "" {
+				tok.Header["kid"] = tt.kid
+			}
+
+			act, err := opt.KeyFn(grappa.Context{}, tok)
+
+			assertErrorExists(t, err, tt.err)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestJWKS_Refresh(t *testing.T) {
+	rk, err := jwt.ParseRSAPublicKeyFromPEM([]byte(rsaPublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sets := [][]byte{
+		newJWKS(rsaJWK("old", "", rk)),
+		newJWKS(rsaJWK("old", "", rk), rsaJWK("new", "", rk)),
+	}
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		exp      int
+		err      bool
+	}{
+		{
+			name:     "should not refresh within the refresh interval",
+			interval: time.Hour,
+			exp:      1,
+			err:      true,
+		},
+		{
+			name:     "should refresh unknown key ids after the refresh interval",
+			interval: 0,
+			exp:      2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var n int
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write(sets[n])
+				n++
+			}))
+			defer svr.Close()
+
+			opt := grappa.Options{}
+			grappa.JWKS(grappa.JWKSURL(svr.URL, svr.Client()), func(o *grappa.JWKSOptions) {
+				o.RefreshInterval = tt.interval
+			})(&opt)
+
+			for _, kid := range []string{"old", "old", "new"} {
+				tok := jwt.New(jwt.SigningMethodRS256)
+				tok.Header["kid"] = kid
+
+				_, err = opt.KeyFn(grappa.Context{}, tok)
+			}
+
+			assertErrorExists(t, err, tt.err)
+			if n != tt.exp {
+				t.Errorf("got %d, expected %d", n, tt.exp)
+			}
+		})
+	}
+}
+
+func TestJWKS_MaxAge(t *testing.T) {
+	rk, err := jwt.ParseRSAPublicKeyFromPEM([]byte(rsaPublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		maxAge time.Duration
+		second func() ([]byte, error)
+		exp    int
+		err    bool
+	}{
+		{
+			name:   "should use cached keys within the max age",
+			maxAge: time.Hour,
+			second: func() ([]byte, error) {
+				return newJWKS(rsaJWK("new", "", rk)), nil
+			},
+			exp: 1,
+		},
+		{
+			name:   "should expire removed keys after the max age",
+			maxAge: 10 * time.Millisecond,
+			second: func() ([]byte, error) {
+				return newJWKS(rsaJWK("new", "", rk)), nil
+			},
+			exp: 3,
+			err: true,
+		},
+		{
+			name:   "should return an error if the set cannot be refreshed after the max age",
+			maxAge: 10 * time.Millisecond,
+			second: func() ([]byte, error) {
+				return nil, os.ErrNotExist
+			},
+			exp: 2,
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var n int
+			src := func() ([]byte, error) {
+				n++
+				if n > 1 {
+					return tt.second()
+				}
+				return newJWKS(rsaJWK("old", "", rk)), nil
+			}
+
+			opt := grappa.Options{}
+			grappa.JWKS(src, func(o *grappa.JWKSOptions) {
+				o.RefreshInterval = 0
+				o.MaxAge = tt.maxAge
+			})(&opt)
+
+			for i := 0; i < 2; i++ {
+				if i > 0 {
+					time.Sleep(20 * time.Millisecond)
+				}
+
+				tok := jwt.New(jwt.SigningMethodRS256)
+				tok.Header["kid"] = "old"
+
+				_, err = opt.KeyFn(grappa.Context{}, tok)
+			}
+
+			assertErrorExists(t, err, tt.err)
+			if n != tt.exp {
+				t.Errorf("got %d, expected %d", n, tt.exp)
+			}
+		})
+	}
+}
+
+func TestJWKS_Concurrency(t *testing.T) {
+	rk, err := jwt.ParseRSAPublicKeyFromPEM([]byte(rsaPublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should not block cached key lookups while refreshing", func(t *testing.T) {
+		fetching, release := make(chan struct{}), make(chan struct{})
+
+		var n int32
+		src := func() ([]byte, error) {
+			if atomic.AddInt32(&n, 1) > 1 {
+				close(fetching)
+				<-release
+			}
+			return newJWKS(rsaJWK("known", "", rk)), nil
+		}
+
+		opt := grappa.Options{}
+		grappa.JWKS(src, func(o *grappa.JWKSOptions) {
+			o.RefreshInterval = 0
+		})(&opt)
+
+		keyFn := func(kid string) error {
+			tok := jwt.New(jwt.SigningMethodRS256)
+			tok.Header["kid"] = kid
+
+			_, err := opt.KeyFn(grappa.Context{}, tok)
+			return err
+		}
+
+		if err := keyFn("known"); err != nil {
+			t.Fatal(err)
+		}
+
+		unknown := make(chan error)
+		go func() {
+			unknown <- keyFn("unknown")
+		}()
+		<-fetching
+
+		known := make(chan error)
+		go func() {
+			known <- keyFn("known")
+		}()
+
+		select {
+		case err := <-known:
+			assertErrorExists(t, err, false)
+		case <-time.After(time.Second):
+			t.Error("cached key lookup blocked by refresh")
+		}
+
+		close(release)
+		assertErrorExists(t, <-unknown, true)
+	})
+}
+
+func TestJWKSFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grappa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name  string
+		setup func(string)
+		exp   []byte
+		err   bool
+	}{
+		{
+			name:  "should return an error if the file does not exist",
+			setup: func(string) {},
+			err:   true,
+		},
+		{
+			name: "should return the file contents",
+			setup: func(p string) {
+				if err := ioutil.WriteFile(p, []byte(`{"keys":[]}`), 0600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			exp: []byte(`{"keys":[]}`),
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(dir, fmt.Sprintf("%d.json", i))
+			tt.setup(p)
+
+			act, err := grappa.JWKSFile(p)()
+
+			assertErrorExists(t, err, tt.err)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestJWKSURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		exp     []byte
+		err     bool
+	}{
+		{
+			name: "should return an error if the status code is not ok",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			err: true,
+		},
+		{
+			name: "should return the response body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"keys":[]}`))
+			},
+			exp: []byte(`{"keys":[]}`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svr := httptest.NewServer(tt.handler)
+			defer svr.Close()
+
+			act, err := grappa.JWKSURL(svr.URL, nil)()
+
+			assertErrorExists(t, err, tt.err)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func newJWKS(keys ...string) []byte {
+	b := new(bytes.Buffer)
+	b.WriteString(`{"keys":[`)
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(k)
+	}
+	b.WriteString(`]}`)
+
+	return b.Bytes()
+}
+
+func rsaJWK(kid, alg string, k *rsa.PublicKey) string {
+	return fmt.Sprintf(`{"kty":"RSA","kid":"%s","alg":"%s","n":"%s","e":"%s"}`,
+		kid, alg, encodeJWK(k.N.Bytes()), encodeJWK(big.NewInt(int64(k.E)).Bytes()))
+}
+
+func encodeJWK(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,116 @@
+package grappa
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
+
+type (
+	// Key represents a verification key with an optional key id
+	Key struct {
+		ID  string
+		Key interface{}
+	}
+
+	keyring []Key
+)
+
+func (r keyring) key(t *jwt.Token) (interface{}, error) {
+	ks := r.candidates(t)
+	if len(ks) < 1 {
+		return nil, fmt.Errorf("key not found for signing method: %s", t.Header["alg"])
+	}
+
+	if len(ks) > 1 {
+		if i := strings.LastIndex(t.Raw, "."); i > 0 {
+			for _, k := range ks {
+				if t.Method.Verify(t.Raw[:i], t.Raw[i+1:], k) == nil {
+					return k, nil
+				}
+			}
+		}
+	}
+
+	return ks[0], nil
+}
+
+func (r keyring) candidates(t *jwt.Token) []interface{} {
+	kid, _ := t.Header["kid"].(string)
+
+	var ids, rest []interface{}
+	for _, k := range r {
+		if verifyMethod(t, k.Key) != nil {
+			continue
+		}
+
+		switch {
+		case kid != "" && k.ID == kid:
+			ids = append(ids, k.Key)
+		case kid == "" || k.ID == "":
+			rest = append(rest, k.Key)
+		}
+	}
+
+	if len(ids) > 0 {
+		return ids
+	}
+
+	return rest
+}
+
+// verifyMethod ensures that the token signing method is valid for the key type
+func verifyMethod(t *jwt.Token, key interface{}) error {
+	var ok bool
+	switch k := key.(type) {
+	case []byte:
+		_, ok = t.Method.(*jwt.SigningMethodHMAC)
+	case *rsa.PublicKey:
+		switch t.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			ok = true
+		}
+	case *ecdsa.PublicKey:
+		if m, mok := t.Method.(*jwt.SigningMethodECDSA); mok {
+			ok = m.CurveBits == k.Curve.Params().BitSize
+		}
+	case ed25519.PublicKey:
+		_, ok = t.Method.(*jwt.SigningMethodEd25519)
+	}
+
+	if !ok {
+		return fmt.Errorf("invalid signing method: %s", t.Header["alg"])
+	}
+
+	return nil
+}
+
+// parsePublicKeyPEM parses a PEM encoded PKIX, PKCS1 or certificate public key
+func parsePublicKeyPEM(b []byte) (interface{}, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("invalid pem key")
+	}
+
+	if k, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return k, nil
+	}
+
+	if k, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return k, nil
+	}
+
+	c, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.New("invalid pem key")
+	}
+
+	return c.PublicKey, nil
+}
@@ -0,0 +1,240 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"sigs.k8s.io/yaml"
+
+	"github.com/stevecallear/grappa"
+	"github.com/stevecallear/grappa/internal/validate"
+	"github.com/stevecallear/grappa/proto/grappapb"
+)
+
+type (
+	// Format represents a rule set file format
+	Format int
+
+	// Reloader represents a registry that supports atomic rule updates
+	Reloader interface {
+		Reload(patterns []string, rules map[string]*grappapb.Rule) error
+	}
+
+	// WatchOptions represents a set of file watch options
+	WatchOptions struct {
+		Interval time.Duration
+		ErrorFn  func(error)
+	}
+
+	loadRegistry interface {
+		Load(rules map[string]*grappapb.Rule) error
+	}
+)
+
+const (
+	// JSON is the protojson rule set format
+	JSON Format = iota
+
+	// YAML is the yaml equivalent of the protojson rule set format
+	YAML
+
+	// Text is the prototext rule set format
+	Text
+)
+
+var defaultWatchOptions = WatchOptions{
+	Interval: 5 * time.Second,
+	ErrorFn:  func(error) {},
+}
+
+// Parse parses and validates the rule set in the specified format
+func Parse(b []byte, f Format) (map[string]*grappapb.Rule, error) {
+	rs := new(grappapb.RuleSet)
+
+	var err error
+	switch f {
+	case JSON:
+		err = protojson.Unmarshal(b, rs)
+	case YAML:
+		if b, err = yaml.YAMLToJSON(b); err == nil {
+			err = protojson.Unmarshal(b, rs)
+		}
+	case Text:
+		err = prototext.Unmarshal(b, rs)
+	default:
+		return nil, fmt.Errorf("unknown format: %d", f)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := ruleSetRules(rs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range patterns(rules) {
+		if err = validate.Pattern(p); err != nil {
+			return nil, err
+		}
+
+		if err = validate.Rule(rules[p]); err != nil {
+			return nil, fmt.Errorf("%s: %v", p, err)
+		}
+	}
+
+	return rules, nil
+}
+
+// ReadFile reads and validates the specified rule set file
+// the format is determined by the file extension
+func ReadFile(path string) (map[string]*grappapb.Rule, error) {
+	f, err := formatOf(path)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := Parse(b, f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return rules, nil
+}
+
+// LoadFile reads the specified rule set file and registers the rules
+// if the registry supports atomic loading, then either all or none of the rules are registered
+func LoadFile(r grappa.Registry, path string) error {
+	rules, err := ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if lr, ok := r.(loadRegistry); ok {
+		return lr.Load(rules)
+	}
+
+	for _, p := range patterns(rules) {
+		r.Register(p, rules[p])
+	}
+
+	return nil
+}
+
+// Watch reads the specified rule set file and registers the rules, replacing
+// them each time the file changes until the context is cancelled
+// reload errors are passed to the error func and the previous rules are retained
+func Watch(ctx context.Context, r Reloader, path string, optFns ...func(*WatchOptions)) error {
+	o := defaultWatchOptions
+	for _, fn := range optFns {
+		fn(&o)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	rules, err := ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err = r.Reload(nil, rules); err != nil {
+		return err
+	}
+
+	go func() {
+		t := time.NewTicker(o.Interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+
+			nfi, err := os.Stat(path)
+			if err != nil {
+				o.ErrorFn(err)
+				continue
+			}
+
+			if nfi.ModTime().Equal(fi.ModTime()) && nfi.Size() == fi.Size() {
+				continue
+			}
+			fi = nfi
+
+			nr, err := ReadFile(path)
+			if err == nil {
+				err = r.Reload(patterns(rules), nr)
+			}
+			if err != nil {
+				o.ErrorFn(err)
+				continue
+			}
+
+			rules = nr
+		}
+	}()
+
+	return nil
+}
+
+// ruleSetRules returns the rules keyed by pattern, including any
+// method rules, such as those in a protoc-gen-grappa manifest
+func ruleSetRules(rs *grappapb.RuleSet) (map[string]*grappapb.Rule, error) {
+	rules := make(map[string]*grappapb.Rule, len(rs.GetRules())+len(rs.GetMethods()))
+	for p, r := range rs.GetRules() {
+		rules[p] = r
+	}
+
+	for _, mr := range rs.GetMethods() {
+		p := mr.GetFullMethod()
+		if r, ok := rules[p]; ok && !proto.Equal(r, mr.GetRule()) {
+			return nil, fmt.Errorf("%s: conflicting rules", p)
+		}
+
+		rules[p] = mr.GetRule()
+	}
+
+	return rules, nil
+}
+
+func formatOf(path string) (Format, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		return JSON, nil
+	case ".yaml", ".yml":
+		return YAML, nil
+	case ".textproto", ".txtpb", ".pbtxt":
+		return Text, nil
+	default:
+		return 0, fmt.Errorf("unknown file extension: %s", ext)
+	}
+}
+
+func patterns(rules map[string]*grappapb.Rule) []string {
+	ps := make([]string, 0, len(rules))
+	for p := range rules {
+		ps = append(ps, p)
+	}
+
+	sort.Strings(ps)
+	return ps
+}
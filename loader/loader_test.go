@@ -0,0 +1,356 @@
+package loader_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/stevecallear/grappa"
+	"github.com/stevecallear/grappa/loader"
+	"github.com/stevecallear/grappa/proto/grappapb"
+)
+
+type registry map[string]*grappapb.Rule
+
+func (r registry) Register(pattern string, pr *grappapb.Rule) {
+	r[pattern] = pr
+}
+
+func TestParse(t *testing.T) {
+	exp := map[string]*grappapb.Rule{
+		"/package.Service/Method": {
+			RequireScope: []string{"read"},
+			RequireClaim: []*grappapb.ClaimRequirement{
+				{Claim: "tenant", Operator: grappapb.ClaimRequirement_IN, Values: []string{"a", "b"}},
+			},
+		},
+		"/package.Service/*": {AllowAnonymous: true},
+	}
+
+	tests := []struct {
+		name   string
+		format loader.Format
+		input  string
+		exp    map[string]*grappapb.Rule
+		err    bool
+	}{
+		{
+			name:   "should return an error if the format is unknown",
+			format: loader.Format(-1),
+			input:  `{}`,
+			err:    true,
+		},
+		{
+			name:   "should return an error if the input is invalid",
+			format: loader.JSON,
+			input:  `{"rules": []}`,
+			err:    true,
+		},
+		{
+			name:   "should return an error if a pattern is invalid",
+			format: loader.JSON,
+			input:  `{"rules": {"/package.*/Method": {}}}`,
+			err:    true,
+		},
+		{
+			name:   "should return an error if a rule is invalid",
+			format: loader.JSON,
+			input:  `{"rules": {"/package.Service/Method": {"scopeExpression": "a &&"}}}`,
+			err:    true,
+		},
+		{
+			name:   "should return an error if a method rule conflicts",
+			format: loader.JSON,
+			input: `{
+	"rules": {"/package.Service/Method": {"allowAnonymous": true}},
+	"methods": [{"full_method": "/package.Service/Method", "rule": {"allowAnonymous": false}}]
+}`,
+			err: true,
+		},
+		{
+			name:   "should parse method rules",
+			format: loader.YAML,
+			input: `methods:
+- full_method: /package.Service/Method
+  rule:
+    require_scope: [read]
+    require_claim:
+    - claim: tenant
+      operator: IN
+      values: [a, b]
+  source: package/service.proto:10:5
+  streaming: SERVER_STREAMING
+rules:
+  /package.Service/*:
+    allow_anonymous: true
+`,
+			exp: exp,
+		},
+		{
+			name:   "should parse json",
+			format: loader.JSON,
+			input: `{
+	"rules": {
+		"/package.Service/Method": {
+			"require_scope": ["read"],
+			"require_claim": [{"claim": "tenant", "operator": "IN", "values": ["a", "b"]}]
+		},
+		"/package.Service/*": {"allowAnonymous": true}
+	}
+}`,
+			exp: exp,
+		},
+		{
+			name:   "should parse yaml",
+			format: loader.YAML,
+			input: `rules:
+  /package.Service/Method:
+    require_scope: [read]
+    require_claim:
+      - claim: tenant
+        operator: IN
+        values: [a, b]
+  /package.Service/*:
+    allow_anonymous: true
+`,
+			exp: exp,
+		},
+		{
+			name:   "should parse text",
+			format: loader.Text,
+			input: `rules {
+	key: "/package.Service/Method"
+	value {
+		require_scope: "read"
+		require_claim { claim: "tenant" operator: IN values: ["a", "b"] }
+	}
+}
+rules {
+	key: "/package.Service/*"
+	value { allow_anonymous: true }
+}`,
+			exp: exp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act, err := loader.Parse([]byte(tt.input), tt.format)
+			assertErrorExists(t, err, tt.err)
+			assertRulesEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		err      bool
+	}{
+		{
+			name:     "should return an error if the extension is unknown",
+			filename: "rules.txt",
+			content:  `{}`,
+			err:      true,
+		},
+		{
+			name:     "should return an error if the file is invalid",
+			filename: "rules.yaml",
+			content:  `rules: [`,
+			err:      true,
+		},
+		{
+			name:     "should read json files",
+			filename: "rules.json",
+			content:  `{"rules": {"/package.Service/Method": {"allowAnonymous": true}}}`,
+		},
+		{
+			name:     "should read yaml files",
+			filename: "rules.yml",
+			content:  "rules:\n  /package.Service/Method:\n    allowAnonymous: true\n",
+		},
+		{
+			name:     "should read text files",
+			filename: "rules.textproto",
+			content:  `rules { key: "/package.Service/Method" value { allow_anonymous: true } }`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, filepath.Join(t.TempDir(), tt.filename), tt.content)
+
+			act, err := loader.ReadFile(path)
+			assertErrorExists(t, err, tt.err)
+
+			if !tt.err && !act["/package.Service/Method"].GetAllowAnonymous() {
+				t.Errorf("got %v, expected an anonymous rule", act)
+			}
+		})
+	}
+
+	t.Run("should return an error if the file does not exist", func(t *testing.T) {
+		_, err := loader.ReadFile(filepath.Join(t.TempDir(), "rules.json"))
+		assertErrorExists(t, err, true)
+	})
+}
+
+func TestLoadFile(t *testing.T) {
+	const content = `{"rules": {"/package.Service/MethodA": {"allowAnonymous": true}, "/package.Service/MethodB": {}}}`
+
+	t.Run("should register the rules", func(t *testing.T) {
+		path := writeFile(t, filepath.Join(t.TempDir(), "rules.json"), content)
+
+		r := registry{}
+		err := loader.LoadFile(r, path)
+		assertErrorExists(t, err, false)
+		assertRulesEqual(t, r, map[string]*grappapb.Rule{
+			"/package.Service/MethodA": {AllowAnonymous: true},
+			"/package.Service/MethodB": {},
+		})
+	})
+
+	t.Run("should load the rules atomically if supported", func(t *testing.T) {
+		path := writeFile(t, filepath.Join(t.TempDir(), "rules.json"), content)
+
+		a := grappa.New()
+		a.Register("/package.Service/MethodB", &grappapb.Rule{RequireScope: []string{"scope"}})
+
+		err := loader.LoadFile(a, path)
+		assertErrorExists(t, err, true)
+
+		if invokeAnonymous(a, "/package.Service/MethodA") == nil {
+			t.Error("got nil, expected an error")
+		}
+	})
+
+	t.Run("should return an error if the file is invalid", func(t *testing.T) {
+		path := writeFile(t, filepath.Join(t.TempDir(), "rules.json"), `{`)
+
+		err := loader.LoadFile(registry{}, path)
+		assertErrorExists(t, err, true)
+	})
+}
+
+func TestWatch(t *testing.T) {
+	t.Run("should return an error if the file is invalid", func(t *testing.T) {
+		path := writeFile(t, filepath.Join(t.TempDir(), "rules.json"), `{`)
+
+		err := loader.Watch(context.Background(), grappa.New(), path)
+		assertErrorExists(t, err, true)
+	})
+
+	t.Run("should reload the rules when the file changes", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		path := writeFile(t, filepath.Join(t.TempDir(), "rules.json"),
+			`{"rules": {"/package.Service/MethodA": {"allowAnonymous": true}}}`)
+
+		errs := make(chan error, 10)
+		a := grappa.New()
+		a.Register("/package.Service/MethodB", &grappapb.Rule{AllowAnonymous: true})
+
+		err := loader.Watch(ctx, a, path, func(o *loader.WatchOptions) {
+			o.Interval = 10 * time.Millisecond
+			o.ErrorFn = func(err error) { errs <- err }
+		})
+		assertErrorExists(t, err, false)
+
+		if err = invokeAnonymous(a, "/package.Service/MethodA"); err != nil {
+			t.Fatalf("got %v, expected nil", err)
+		}
+
+		touch(t, writeFile(t, path, `{"rules": {"/package.Service/MethodA": {"scopeExpression": "a &&"}}}`), 1)
+
+		select {
+		case <-errs:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for reload error")
+		}
+
+		if err = invokeAnonymous(a, "/package.Service/MethodA"); err != nil {
+			t.Errorf("got %v, expected the previous rules to be retained", err)
+		}
+
+		touch(t, writeFile(t, path, `{"rules": {"/package.Service/MethodC": {"allowAnonymous": true}}}`), 2)
+
+		eventually(t, func() bool {
+			return invokeAnonymous(a, "/package.Service/MethodC") == nil
+		})
+
+		if err = invokeAnonymous(a, "/package.Service/MethodA"); err == nil {
+			t.Error("got nil, expected the removed rule to be unregistered")
+		}
+
+		if err = invokeAnonymous(a, "/package.Service/MethodB"); err != nil {
+			t.Errorf("got %v, expected other rules to be retained", err)
+		}
+	})
+}
+
+func writeFile(t *testing.T, path, content string) string {
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// touch sets a distinct modification time to avoid relying on file system timestamp resolution
+func touch(t *testing.T, path string, n int) {
+	mt := time.Now().Add(time.Duration(n) * time.Minute)
+	if err := os.Chtimes(path, mt, mt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func eventually(t *testing.T, fn func() bool) {
+	for d := time.Now().Add(time.Second); time.Now().Before(d); time.Sleep(10 * time.Millisecond) {
+		if fn() {
+			return
+		}
+	}
+
+	t.Fatal("timed out waiting for condition")
+}
+
+func invokeAnonymous(a *grappa.Authorizor, fullMethod string) error {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{})
+	info := &grpc.UnaryServerInfo{FullMethod: fullMethod}
+
+	_, err := a.UnaryInterceptor(ctx, nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	return err
+}
+
+func assertErrorExists(t *testing.T, act error, exp bool) {
+	if act != nil && !exp {
+		t.Errorf("got %v, expected nil", act)
+	}
+	if act == nil && exp {
+		t.Error("got nil, expected an error")
+	}
+}
+
+func assertRulesEqual(t *testing.T, act, exp map[string]*grappapb.Rule) {
+	if len(act) != len(exp) {
+		t.Fatalf("got %v, expected %v", act, exp)
+	}
+
+	for p, er := range exp {
+		if ar, ok := act[p]; !ok || !proto.Equal(ar, er) {
+			t.Errorf("got %v, expected %v for %s", ar, er, p)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package grappa
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"errors"
 	"fmt"
@@ -14,12 +17,17 @@ import (
 
 // Options represents a set of auth options
 type Options struct {
-	TokenFn         func(Context, metadata.MD) (string, bool)
-	KeyFn           func(Context, *jwt.Token) (interface{}, error)
-	ErrorFn         func(Context, error) error
-	ClaimsVerifiers []VerifyFunc
-	ClaimsMap       map[string]string
-	Optional        bool
+	TokenFn               func(Context, metadata.MD) (string, bool)
+	KeyFn                 func(Context, *jwt.Token) (interface{}, error)
+	IntrospectFn          func(context.Context, Context, string) (jwt.MapClaims, error)
+	ErrorFn               func(Context, error) error
+	ClaimsFn              func() jwt.Claims
+	ClaimsVerifiers       []VerifyFunc
+	CustomClaimsVerifiers []CustomVerifyFunc
+	ClaimsMap             map[string]string
+	Policies              map[string]PolicyFunc
+	Algorithms            []string
+	Optional              bool
 }
 
 var defaultOptions = Options{
@@ -67,13 +75,64 @@ func RSA(key *rsa.PublicKey) func(*Options) {
 	}
 }
 
-// VerifyClaims configues the authorizor to use default issuer, audience and scope verification
+// ECDSA configures the middleware to use the specified ECDSA key
+func ECDSA(key *ecdsa.PublicKey) func(*Options) {
+	return staticKey(key)
+}
+
+// Ed25519 configures the middleware to use the specified Ed25519 key
+func Ed25519(key ed25519.PublicKey) func(*Options) {
+	return staticKey(key)
+}
+
+// PublicKeyPEM configures the middleware to use the specified PEM key
+// the key type is detected automatically and can be RSA, ECDSA or Ed25519
+func PublicKeyPEM(key []byte) (func(*Options), error) {
+	k, err := parsePublicKeyPEM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	switch tk := k.(type) {
+	case *rsa.PublicKey:
+		return RSA(tk), nil
+	case *ecdsa.PublicKey:
+		return ECDSA(tk), nil
+	case ed25519.PublicKey:
+		return Ed25519(tk), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %T", k)
+	}
+}
+
+// Keyring configures the middleware to use any of the specified keys
+// keys with an id are selected by the token kid header, otherwise each key
+// that supports the token signing method is tried in turn
+func Keyring(keys ...Key) func(*Options) {
+	return func(o *Options) {
+		o.KeyFn = func(_ Context, t *jwt.Token) (interface{}, error) {
+			return keyring(keys).key(t)
+		}
+	}
+}
+
+// Algorithms configures the authorizor to reject tokens that are not signed
+// with one of the specified algorithms, e.g. grappa.Algorithms("RS256", "ES256")
+func Algorithms(algs ...string) func(*Options) {
+	return func(o *Options) {
+		o.Algorithms = algs
+	}
+}
+
+// VerifyClaims configues the authorizor to use default issuer, audience, scope
+// and required claim verification
 func VerifyClaims(iss, aud string) func(*Options) {
 	return func(o *Options) {
 		o.ClaimsVerifiers = append(o.ClaimsVerifiers,
 			VerifyIssuer(iss),
 			VerifyAudience([]string{aud}),
-			VerifyScope())
+			VerifyScope(),
+			VerifyRequiredClaims())
 	}
 }
 
@@ -93,8 +152,30 @@ func CaptureClaim(claim, metadataKey string) func(*Options) {
 	}
 }
 
+// CustomClaims configures the authorizor to decode verified tokens into the
+// claims type returned by fn and verify them using the specified funcs
+// e.g. grappa.CustomClaims(func() jwt.Claims { return new(MyClaims) })
+func CustomClaims(fn func() jwt.Claims, verifiers ...CustomVerifyFunc) func(*Options) {
+	return func(o *Options) {
+		o.ClaimsFn = fn
+		o.CustomClaimsVerifiers = append(o.CustomClaimsVerifiers, verifiers...)
+	}
+}
+
 // Optional configures the authorizor to allow anonymous access to methods that
 // do not have a rule specified
 func Optional(o *Options) {
 	o.Optional = true
 }
+
+func staticKey(key interface{}) func(*Options) {
+	return func(o *Options) {
+		o.KeyFn = func(_ Context, t *jwt.Token) (interface{}, error) {
+			if err := verifyMethod(t, key); err != nil {
+				return nil, err
+			}
+
+			return key, nil
+		}
+	}
+}
@@ -1,10 +1,20 @@
 package grappa_test
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 
 	"github.com/stevecallear/grappa"
 	"github.com/stevecallear/grappa/proto/grappapb"
@@ -84,6 +94,240 @@ func TestRSA(t *testing.T) {
 	}
 }
 
+func TestECDSA(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	tests := []struct {
+		name   string
+		method jwt.SigningMethod
+		exp    interface{}
+		err    bool
+	}{
+		{
+			name:   "should return an error if the signing method is invalid",
+			method: jwt.SigningMethodHS256,
+			err:    true,
+		},
+		{
+			name:   "should return an error if the curve does not match the signing method",
+			method: jwt.SigningMethodES384,
+			err:    true,
+		},
+		{
+			name:   "should return the key if the signing method is valid",
+			method: jwt.SigningMethodES256,
+			exp:    &key.PublicKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := grappa.Options{}
+			grappa.ECDSA(&key.PublicKey)(&opt)
+
+			act, err := opt.KeyFn(grappa.Context{}, jwt.New(tt.method))
+
+			assertErrorExists(t, err, tt.err)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestEd25519(t *testing.T) {
+	key, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	tests := []struct {
+		name   string
+		method jwt.SigningMethod
+		exp    interface{}
+		err    bool
+	}{
+		{
+			name:   "should return an error if the signing method is invalid",
+			method: jwt.SigningMethodHS256,
+			err:    true,
+		},
+		{
+			name:   "should return the key if the signing method is valid",
+			method: jwt.SigningMethodEdDSA,
+			exp:    key,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := grappa.Options{}
+			grappa.Ed25519(key)(&opt)
+
+			act, err := opt.KeyFn(grappa.Context{}, jwt.New(tt.method))
+
+			assertErrorExists(t, err, tt.err)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestPublicKeyPEM(t *testing.T) {
+	rk, err := jwt.ParseRSAPublicKeyFromPEM([]byte(rsaPublicKey))
+	if err != nil {
+		panic(err)
+	}
+
+	ek, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	edk, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	tests := []struct {
+		name   string
+		key    []byte
+		method jwt.SigningMethod
+		exp    interface{}
+		err    bool
+	}{
+		{
+			name: "should return an error if the pem is invalid",
+			key:  []byte("invalid"),
+			err:  true,
+		},
+		{
+			name: "should return an error if the key is invalid",
+			key:  pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("invalid")}),
+			err:  true,
+		},
+		{
+			name:   "should detect pkix rsa keys",
+			key:    []byte(rsaPublicKey),
+			method: jwt.SigningMethodRS256,
+			exp:    rk,
+		},
+		{
+			name:   "should detect pkcs1 rsa keys",
+			key:    pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(rk)}),
+			method: jwt.SigningMethodRS256,
+			exp:    rk,
+		},
+		{
+			name:   "should detect ecdsa keys",
+			key:    marshalPKIX(&ek.PublicKey),
+			method: jwt.SigningMethodES384,
+			exp:    &ek.PublicKey,
+		},
+		{
+			name:   "should detect ed25519 keys",
+			key:    marshalPKIX(edk),
+			method: jwt.SigningMethodEdDSA,
+			exp:    edk,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn, err := grappa.PublicKeyPEM(tt.key)
+			assertErrorExists(t, err, tt.err)
+			if err != nil {
+				return
+			}
+
+			opt := grappa.Options{}
+			fn(&opt)
+
+			act, err := opt.KeyFn(grappa.Context{}, jwt.New(tt.method))
+
+			assertErrorExists(t, err, false)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	rk, err := jwt.ParseRSAPublicKeyFromPEM([]byte(rsaPublicKey))
+	if err != nil {
+		panic(err)
+	}
+
+	nk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	hk := []byte("secret")
+
+	tests := []struct {
+		name  string
+		keys  []grappa.Key
+		token func() string
+		err   bool
+	}{
+		{
+			name: "should return an error if no key supports the signing method",
+			keys: []grappa.Key{{Key: rk}},
+			token: func() string {
+				return newHMAC(hk, jwt.MapClaims{})
+			},
+			err: true,
+		},
+		{
+			name: "should only use the key matching the id",
+			keys: []grappa.Key{{ID: "new", Key: &nk.PublicKey}, {ID: "old", Key: rk}},
+			token: func() string {
+				return newToken(jwt.SigningMethodRS256, "old", nk, jwt.MapClaims{})
+			},
+			err: true,
+		},
+		{
+			name: "should fall back to keys without an id if the id is unknown",
+			keys: []grappa.Key{{ID: "new", Key: &nk.PublicKey}, {Key: rk}},
+			token: func() string {
+				return newRSA([]byte(rsaPrivateKey), jwt.MapClaims{})
+			},
+		},
+		{
+			name: "should try each key that supports the signing method",
+			keys: []grappa.Key{{Key: hk}, {Key: &nk.PublicKey}, {Key: rk}},
+			token: func() string {
+				return newRSA([]byte(rsaPrivateKey), jwt.MapClaims{})
+			},
+		},
+		{
+			name: "should select symmetric keys",
+			keys: []grappa.Key{{Key: rk}, {ID: "hmac", Key: hk}},
+			token: func() string {
+				return newToken(jwt.SigningMethodHS256, "hmac", hk, jwt.MapClaims{})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := grappa.Options{}
+			grappa.Keyring(tt.keys...)(&opt)
+
+			_, err := jwt.Parse(tt.token(), func(t *jwt.Token) (interface{}, error) {
+				return opt.KeyFn(grappa.Context{}, t)
+			})
+
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
+
+func TestAlgorithms(t *testing.T) {
+	t.Run("should set the allowed algorithms", func(t *testing.T) {
+		opt := grappa.Options{}
+		grappa.Algorithms("RS256", "ES256")(&opt)
+
+		assertDeepEqual(t, opt.Algorithms, []string{"RS256", "ES256"})
+	})
+}
+
 func TestVerifyClaims(t *testing.T) {
 	t.Run("should add the claims verification funcs", func(t *testing.T) {
 		var iss, aud, scope = "issuer", "audience", "scope"
@@ -103,8 +347,8 @@ func TestVerifyClaims(t *testing.T) {
 			"scope": scope,
 		}
 
-		if len(o.ClaimsVerifiers) != 3 {
-			t.Errorf("got %d, expected 3 funcs", len(o.ClaimsVerifiers))
+		if len(o.ClaimsVerifiers) != 4 {
+			t.Errorf("got %d, expected 4 funcs", len(o.ClaimsVerifiers))
 		}
 
 		for _, fn := range o.ClaimsVerifiers {
@@ -114,6 +358,163 @@ func TestVerifyClaims(t *testing.T) {
 	})
 }
 
+func TestVerifyClaims_Rules(t *testing.T) {
+	const iss, aud = "issuer", "audience"
+
+	tests := []struct {
+		name   string
+		rule   *grappapb.Rule
+		claims jwt.MapClaims
+		req    interface{}
+		err    bool
+	}{
+		{
+			name:   "should return an error if the rule has no requirements and the scope claim is missing",
+			rule:   &grappapb.Rule{},
+			claims: jwt.MapClaims{},
+			err:    true,
+		},
+		{
+			name: "should return an error if the required claims are not satisfied",
+			rule: &grappapb.Rule{
+				RequireClaim: []*grappapb.ClaimRequirement{{Claim: "tier", Values: []string{"premium"}}},
+			},
+			claims: jwt.MapClaims{"tier": "basic"},
+			err:    true,
+		},
+		{
+			name: "should allow rules that only require claims",
+			rule: &grappapb.Rule{
+				RequireClaim: []*grappapb.ClaimRequirement{{Claim: "tier", Values: []string{"premium"}}},
+			},
+			claims: jwt.MapClaims{"tier": "premium"},
+		},
+		{
+			name:   "should return an error if the required roles are not satisfied",
+			rule:   &grappapb.Rule{RequireRole: []string{"admin"}},
+			claims: jwt.MapClaims{"roles": []string{"user"}},
+			err:    true,
+		},
+		{
+			name:   "should allow rules that only require roles",
+			rule:   &grappapb.Rule{RequireRole: []string{"admin"}},
+			claims: jwt.MapClaims{"roles": []string{"admin"}},
+		},
+		{
+			name:   "should return an error if the condition is not satisfied",
+			rule:   &grappapb.Rule{Condition: "claims.sub == 'alice'"},
+			claims: jwt.MapClaims{"sub": "bob"},
+			err:    true,
+		},
+		{
+			name:   "should allow rules that only specify a condition",
+			rule:   &grappapb.Rule{Condition: "claims.sub == 'alice'"},
+			claims: jwt.MapClaims{"sub": "alice"},
+		},
+		{
+			name:   "should return an error if the policy denies the request",
+			rule:   &grappapb.Rule{Policy: "alice"},
+			claims: jwt.MapClaims{"sub": "bob"},
+			err:    true,
+		},
+		{
+			name:   "should allow rules that only specify a policy",
+			rule:   &grappapb.Rule{Policy: "alice"},
+			claims: jwt.MapClaims{"sub": "alice"},
+		},
+		{
+			name:   "should return an error if the field binding is not satisfied",
+			rule:   &grappapb.Rule{BindField: []*grappapb.FieldBinding{{Field: "full_method", Claim: "sub"}}},
+			claims: jwt.MapClaims{"sub": "bob"},
+			req:    &grappapb.MethodRule{FullMethod: "alice"},
+			err:    true,
+		},
+		{
+			name:   "should allow rules that only specify field bindings",
+			rule:   &grappapb.Rule{BindField: []*grappapb.FieldBinding{{Field: "full_method", Claim: "sub"}}},
+			claims: jwt.MapClaims{"sub": "alice"},
+			req:    &grappapb.MethodRule{FullMethod: "alice"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := grappa.New(grappa.HMAC([]byte("secretkey")), grappa.VerifyClaims(iss, aud), func(o *grappa.Options) {
+				o.ClaimsVerifiers = append(o.ClaimsVerifiers, grappa.VerifyRoles("roles"))
+			}, grappa.Policy("alice", func(_ context.Context, _ grappa.Context, c jwt.MapClaims, _ interface{}) (grappa.Decision, error) {
+				if c["sub"] == "alice" {
+					return grappa.Allow(), nil
+				}
+				return grappa.Deny("not alice"), nil
+			}))
+			sut.Register("/package.Service/Method", tt.rule)
+
+			c := jwt.MapClaims{"iss": iss, "aud": aud, "exp": time.Now().Add(time.Hour).Unix()}
+			for k, v := range tt.claims {
+				c[k] = v
+			}
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+newHMAC([]byte("secretkey"), c)))
+			info := &grpc.UnaryServerInfo{FullMethod: "/package.Service/Method"}
+
+			_, err := sut.UnaryInterceptor(ctx, tt.req, info, func(context.Context, interface{}) (interface{}, error) {
+				return nil, nil
+			})
+
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
+
+func TestVerifyClaims_Unverified(t *testing.T) {
+	const iss, aud = "issuer", "audience"
+
+	tests := []struct {
+		name   string
+		optFn  func(*grappa.Options)
+		rule   *grappapb.Rule
+		claims jwt.MapClaims
+	}{
+		{
+			name:   "should return an error if roles are required but not verified",
+			optFn:  grappa.VerifyClaims(iss, aud),
+			rule:   &grappapb.Rule{RequireRole: []string{"admin"}},
+			claims: jwt.MapClaims{"roles": []string{"admin"}},
+		},
+		{
+			name: "should return an error if claims are required but not verified",
+			optFn: func(o *grappa.Options) {
+				o.ClaimsVerifiers = append(o.ClaimsVerifiers, grappa.VerifyScope("scope", "scp"))
+			},
+			rule: &grappapb.Rule{
+				RequireClaim: []*grappapb.ClaimRequirement{{Claim: "tier", Values: []string{"premium"}}},
+			},
+			claims: jwt.MapClaims{"tier": "premium"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := grappa.New(grappa.HMAC([]byte("secretkey")), tt.optFn)
+			sut.Register("/package.Service/Method", tt.rule)
+
+			c := jwt.MapClaims{"iss": iss, "aud": aud, "exp": time.Now().Add(time.Hour).Unix()}
+			for k, v := range tt.claims {
+				c[k] = v
+			}
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+newHMAC([]byte("secretkey"), c)))
+			info := &grpc.UnaryServerInfo{FullMethod: "/package.Service/Method"}
+
+			_, err := sut.UnaryInterceptor(ctx, nil, info, func(context.Context, interface{}) (interface{}, error) {
+				return nil, nil
+			})
+
+			assertErrorExists(t, err, true)
+		})
+	}
+}
+
 func TestCaptureClaim(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -156,6 +557,30 @@ func TestCaptureClaim(t *testing.T) {
 	}
 }
 
+func TestCustomClaims(t *testing.T) {
+	t.Run("should set the claims func and append verifiers", func(t *testing.T) {
+		opt := grappa.Options{
+			CustomClaimsVerifiers: []grappa.CustomVerifyFunc{
+				func(grappa.Context, jwt.Claims) error { return nil },
+			},
+		}
+
+		grappa.CustomClaims(func() jwt.Claims {
+			return new(jwt.StandardClaims)
+		}, func(grappa.Context, jwt.Claims) error {
+			return nil
+		})(&opt)
+
+		if _, ok := opt.ClaimsFn().(*jwt.StandardClaims); !ok {
+			t.Errorf("got %T, expected *jwt.StandardClaims", opt.ClaimsFn())
+		}
+
+		if act, exp := len(opt.CustomClaimsVerifiers), 2; act != exp {
+			t.Errorf("got %d, expected %d", act, exp)
+		}
+	})
+}
+
 func TestOptional(t *testing.T) {
 	t.Run("should set the optional flag to true", func(t *testing.T) {
 		opt := grappa.Options{}
@@ -166,3 +591,12 @@ func TestOptional(t *testing.T) {
 		}
 	})
 }
+
+func marshalPKIX(k interface{}) []byte {
+	b, err := x509.MarshalPKIXPublicKey(k)
+	if err != nil {
+		panic(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b})
+}
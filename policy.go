@@ -0,0 +1,90 @@
+package grappa
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang-jwt/jwt"
+)
+
+type (
+	// PolicyFunc represents a request authorization policy func
+	// the claims are nil for anonymous requests, and the request is the
+	// decoded request message, or each received message for streams
+	// for streams the policy is also evaluated with a nil request when the stream starts
+	PolicyFunc func(ctx context.Context, rctx Context, claims jwt.MapClaims, req interface{}) (Decision, error)
+
+	// Decision represents a policy decision
+	Decision struct {
+		Allow  bool
+		Reason string
+	}
+
+	// PolicyError represents a policy deny decision
+	PolicyError struct {
+		Policy string
+		Reason string
+	}
+)
+
+// Allow returns a decision that allows the request
+func Allow() Decision {
+	return Decision{Allow: true}
+}
+
+// Deny returns a decision that denies the request for the specified reason
+func Deny(reason string) Decision {
+	return Decision{Reason: reason}
+}
+
+// Policy configures the authorizor to evaluate fn for rules with the specified policy name
+// e.g. grappa.Policy("account_owner", fn)
+func Policy(name string, fn PolicyFunc) func(*Options) {
+	return func(o *Options) {
+		if o.Policies == nil {
+			o.Policies = map[string]PolicyFunc{
+				name: fn,
+			}
+			return
+		}
+
+		o.Policies[name] = fn
+	}
+}
+
+// Error returns the error message
+func (e *PolicyError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("policy %s denied the request", e.Policy)
+	}
+
+	return fmt.Sprintf("policy %s denied the request: %s", e.Policy, e.Reason)
+}
+
+// evaluatePolicy evaluates the policy for the authorized request context
+func (a *Authorizor) evaluatePolicy(ctx context.Context, req interface{}) error {
+	rctx, _ := FromContext(ctx)
+
+	name := rctx.Rule.GetPolicy()
+	if name == "" {
+		return nil
+	}
+
+	fn, ok := a.opts.Policies[name]
+	if !ok {
+		return a.opts.ErrorFn(rctx, fmt.Errorf("policy not found: %s", name))
+	}
+
+	claims, _ := ClaimsFromContext(ctx)
+
+	d, err := fn(ctx, rctx, claims, req)
+	if err != nil {
+		return a.opts.ErrorFn(rctx, err)
+	}
+
+	if !d.Allow {
+		return a.opts.ErrorFn(rctx, &PolicyError{Policy: name, Reason: d.Reason})
+	}
+
+	return nil
+}
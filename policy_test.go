@@ -0,0 +1,177 @@
+package grappa_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"google.golang.org/grpc"
+
+	"github.com/stevecallear/grappa"
+	"github.com/stevecallear/grappa/proto/grappapb"
+)
+
+// ownerPolicy allows requests where the request message is the token subject
+// streams are allowed to start for any authenticated subject
+func ownerPolicy(_ context.Context, _ grappa.Context, c jwt.MapClaims, req interface{}) (grappa.Decision, error) {
+	sub, _ := c["sub"].(string)
+	if sub != "" && req == nil {
+		return grappa.Allow(), nil
+	}
+
+	if r, ok := req.(*string); ok && sub != "" && *r == sub {
+		return grappa.Allow(), nil
+	}
+
+	return grappa.Deny("not the owner"), nil
+}
+
+func TestPolicy_UnaryInterceptor(t *testing.T) {
+	claims := jwt.MapClaims{"sub": "subject"}
+
+	tests := []struct {
+		name   string
+		rule   *grappapb.Rule
+		policy grappa.PolicyFunc
+		claims jwt.MapClaims
+		req    string
+		err    error
+	}{
+		{
+			name:   "should return an error if the policy denies the request",
+			rule:   &grappapb.Rule{Policy: "owner"},
+			policy: ownerPolicy,
+			claims: claims,
+			req:    "other",
+			err:    &grappa.PolicyError{Policy: "owner", Reason: "not the owner"},
+		},
+		{
+			name: "should return an error if the policy fails",
+			rule: &grappapb.Rule{Policy: "owner"},
+			policy: func(context.Context, grappa.Context, jwt.MapClaims, interface{}) (grappa.Decision, error) {
+				return grappa.Allow(), errors.New("error")
+			},
+			claims: claims,
+			req:    "subject",
+			err:    errors.New("error"),
+		},
+		{
+			name:   "should evaluate policies for anonymous requests",
+			rule:   &grappapb.Rule{AllowAnonymous: true, Policy: "owner"},
+			policy: ownerPolicy,
+			req:    "subject",
+			err:    &grappa.PolicyError{Policy: "owner", Reason: "not the owner"},
+		},
+		{
+			name:   "should not evaluate policies for rules without a policy",
+			rule:   &grappapb.Rule{},
+			policy: ownerPolicy,
+			claims: claims,
+			req:    "other",
+		},
+		{
+			name:   "should allow requests if the policy allows the request",
+			rule:   &grappapb.Rule{Policy: "owner"},
+			policy: ownerPolicy,
+			claims: claims,
+			req:    "subject",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := newPolicyAuthorizor(tt.policy)
+			sut.Register("/package.Service/Method", tt.rule)
+
+			info := &grpc.UnaryServerInfo{FullMethod: "/package.Service/Method"}
+			_, err := sut.UnaryInterceptor(newTokenContext(tt.claims), &tt.req, info, func(context.Context, interface{}) (interface{}, error) {
+				return nil, nil
+			})
+
+			assertDeepEqual(t, err, tt.err)
+		})
+	}
+}
+
+func TestPolicy_StreamInterceptor(t *testing.T) {
+	claims := jwt.MapClaims{"sub": "subject"}
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		msgs   []string
+		exp    []string
+		err    error
+	}{
+		{
+			name: "should return an error if the policy denies the stream",
+			err:  &grappa.PolicyError{Policy: "owner", Reason: "not the owner"},
+		},
+		{
+			name:   "should return an error if the policy denies a message",
+			claims: claims,
+			msgs:   []string{"subject", "other", "subject"},
+			exp:    []string{"subject"},
+			err:    &grappa.PolicyError{Policy: "owner", Reason: "not the owner"},
+		},
+		{
+			name:   "should evaluate the policy for each message",
+			claims: claims,
+			msgs:   []string{"subject", "subject"},
+			exp:    []string{"subject", "subject"},
+			err:    io.EOF,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := newPolicyAuthorizor(ownerPolicy)
+			sut.Register("/package.Service/Method", &grappapb.Rule{AllowAnonymous: true, Policy: "owner"})
+
+			info := &grpc.StreamServerInfo{FullMethod: "/package.Service/Method"}
+			ss := &serverStream{ctx: newTokenContext(tt.claims)}
+			for _, m := range tt.msgs {
+				ss.msgs = append(ss.msgs, m)
+			}
+
+			var act []string
+			err := sut.StreamInterceptor(nil, ss, info, func(_ interface{}, ss grpc.ServerStream) error {
+				for {
+					var m string
+					if err := ss.RecvMsg(&m); err != nil {
+						return err
+					}
+					act = append(act, m)
+				}
+			})
+
+			assertDeepEqual(t, err, tt.err)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestPolicy_Register(t *testing.T) {
+	t.Run("should return an error if the policy is not configured", func(t *testing.T) {
+		sut := newPolicyAuthorizor(ownerPolicy)
+
+		err := sut.Load(map[string]*grappapb.Rule{
+			"/package.Service/Method": {Policy: "unknown"},
+		})
+
+		assertErrorExists(t, err, true)
+	})
+}
+
+func newPolicyAuthorizor(fn grappa.PolicyFunc) *grappa.Authorizor {
+	return grappa.New(
+		grappa.HMAC([]byte("secretkey")),
+		grappa.Policy("owner", fn),
+		func(o *grappa.Options) {
+			o.ErrorFn = func(_ grappa.Context, err error) error {
+				return err
+			}
+		})
+}
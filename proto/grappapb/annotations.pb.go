@@ -21,13 +21,169 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type MethodRule_Streaming int32
+
+const (
+	MethodRule_UNARY            MethodRule_Streaming = 0
+	MethodRule_CLIENT_STREAMING MethodRule_Streaming = 1
+	MethodRule_SERVER_STREAMING MethodRule_Streaming = 2
+	MethodRule_BIDI_STREAMING   MethodRule_Streaming = 3
+)
+
+// Enum value maps for MethodRule_Streaming.
+var (
+	MethodRule_Streaming_name = map[int32]string{
+		0: "UNARY",
+		1: "CLIENT_STREAMING",
+		2: "SERVER_STREAMING",
+		3: "BIDI_STREAMING",
+	}
+	MethodRule_Streaming_value = map[string]int32{
+		"UNARY":            0,
+		"CLIENT_STREAMING": 1,
+		"SERVER_STREAMING": 2,
+		"BIDI_STREAMING":   3,
+	}
+)
+
+func (x MethodRule_Streaming) Enum() *MethodRule_Streaming {
+	p := new(MethodRule_Streaming)
+	*p = x
+	return p
+}
+
+func (x MethodRule_Streaming) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MethodRule_Streaming) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_grappapb_annotations_proto_enumTypes[0].Descriptor()
+}
+
+func (MethodRule_Streaming) Type() protoreflect.EnumType {
+	return &file_proto_grappapb_annotations_proto_enumTypes[0]
+}
+
+func (x MethodRule_Streaming) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MethodRule_Streaming.Descriptor instead.
+func (MethodRule_Streaming) EnumDescriptor() ([]byte, []int) {
+	return file_proto_grappapb_annotations_proto_rawDescGZIP(), []int{2, 0}
+}
+
+type ClaimRequirement_Operator int32
+
+const (
+	ClaimRequirement_EQUALS ClaimRequirement_Operator = 0
+	ClaimRequirement_IN     ClaimRequirement_Operator = 1
+	ClaimRequirement_EXISTS ClaimRequirement_Operator = 2
+	ClaimRequirement_PREFIX ClaimRequirement_Operator = 3
+)
+
+// Enum value maps for ClaimRequirement_Operator.
+var (
+	ClaimRequirement_Operator_name = map[int32]string{
+		0: "EQUALS",
+		1: "IN",
+		2: "EXISTS",
+		3: "PREFIX",
+	}
+	ClaimRequirement_Operator_value = map[string]int32{
+		"EQUALS": 0,
+		"IN":     1,
+		"EXISTS": 2,
+		"PREFIX": 3,
+	}
+)
+
+func (x ClaimRequirement_Operator) Enum() *ClaimRequirement_Operator {
+	p := new(ClaimRequirement_Operator)
+	*p = x
+	return p
+}
+
+func (x ClaimRequirement_Operator) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ClaimRequirement_Operator) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_grappapb_annotations_proto_enumTypes[1].Descriptor()
+}
+
+func (ClaimRequirement_Operator) Type() protoreflect.EnumType {
+	return &file_proto_grappapb_annotations_proto_enumTypes[1]
+}
+
+func (x ClaimRequirement_Operator) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ClaimRequirement_Operator.Descriptor instead.
+func (ClaimRequirement_Operator) EnumDescriptor() ([]byte, []int) {
+	return file_proto_grappapb_annotations_proto_rawDescGZIP(), []int{3, 0}
+}
+
+type FieldBinding_Operator int32
+
+const (
+	FieldBinding_EQUALS FieldBinding_Operator = 0
+	FieldBinding_IN     FieldBinding_Operator = 1
+)
+
+// Enum value maps for FieldBinding_Operator.
+var (
+	FieldBinding_Operator_name = map[int32]string{
+		0: "EQUALS",
+		1: "IN",
+	}
+	FieldBinding_Operator_value = map[string]int32{
+		"EQUALS": 0,
+		"IN":     1,
+	}
+)
+
+func (x FieldBinding_Operator) Enum() *FieldBinding_Operator {
+	p := new(FieldBinding_Operator)
+	*p = x
+	return p
+}
+
+func (x FieldBinding_Operator) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (FieldBinding_Operator) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_grappapb_annotations_proto_enumTypes[2].Descriptor()
+}
+
+func (FieldBinding_Operator) Type() protoreflect.EnumType {
+	return &file_proto_grappapb_annotations_proto_enumTypes[2]
+}
+
+func (x FieldBinding_Operator) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use FieldBinding_Operator.Descriptor instead.
+func (FieldBinding_Operator) EnumDescriptor() ([]byte, []int) {
+	return file_proto_grappapb_annotations_proto_rawDescGZIP(), []int{4, 0}
+}
+
 type Rule struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	AllowAnonymous bool     `protobuf:"varint,1,opt,name=allow_anonymous,json=allowAnonymous,proto3" json:"allow_anonymous,omitempty"`
-	RequireScope   []string `protobuf:"bytes,2,rep,name=require_scope,json=requireScope,proto3" json:"require_scope,omitempty"`
+	AllowAnonymous  bool                `protobuf:"varint,1,opt,name=allow_anonymous,json=allowAnonymous,proto3" json:"allow_anonymous,omitempty"`
+	RequireScope    []string            `protobuf:"bytes,2,rep,name=require_scope,json=requireScope,proto3" json:"require_scope,omitempty"`
+	ScopeExpression string              `protobuf:"bytes,3,opt,name=scope_expression,json=scopeExpression,proto3" json:"scope_expression,omitempty"`
+	RequireClaim    []*ClaimRequirement `protobuf:"bytes,4,rep,name=require_claim,json=requireClaim,proto3" json:"require_claim,omitempty"`
+	RequireRole     []string            `protobuf:"bytes,5,rep,name=require_role,json=requireRole,proto3" json:"require_role,omitempty"`
+	Policy          string              `protobuf:"bytes,6,opt,name=policy,proto3" json:"policy,omitempty"`
+	BindField       []*FieldBinding     `protobuf:"bytes,7,rep,name=bind_field,json=bindField,proto3" json:"bind_field,omitempty"`
+	Condition       string              `protobuf:"bytes,8,opt,name=condition,proto3" json:"condition,omitempty"`
 }
 
 func (x *Rule) Reset() {
@@ -76,7 +232,317 @@ func (x *Rule) GetRequireScope() []string {
 	return nil
 }
 
+func (x *Rule) GetScopeExpression() string {
+	if x != nil {
+		return x.ScopeExpression
+	}
+	return ""
+}
+
+func (x *Rule) GetRequireClaim() []*ClaimRequirement {
+	if x != nil {
+		return x.RequireClaim
+	}
+	return nil
+}
+
+func (x *Rule) GetRequireRole() []string {
+	if x != nil {
+		return x.RequireRole
+	}
+	return nil
+}
+
+func (x *Rule) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+func (x *Rule) GetBindField() []*FieldBinding {
+	if x != nil {
+		return x.BindField
+	}
+	return nil
+}
+
+func (x *Rule) GetCondition() string {
+	if x != nil {
+		return x.Condition
+	}
+	return ""
+}
+
+type RuleSet struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Rules   map[string]*Rule `protobuf:"bytes,1,rep,name=rules,proto3" json:"rules,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Methods []*MethodRule    `protobuf:"bytes,2,rep,name=methods,proto3" json:"methods,omitempty"`
+}
+
+func (x *RuleSet) Reset() {
+	*x = RuleSet{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_grappapb_annotations_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RuleSet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuleSet) ProtoMessage() {}
+
+func (x *RuleSet) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grappapb_annotations_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuleSet.ProtoReflect.Descriptor instead.
+func (*RuleSet) Descriptor() ([]byte, []int) {
+	return file_proto_grappapb_annotations_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RuleSet) GetRules() map[string]*Rule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+func (x *RuleSet) GetMethods() []*MethodRule {
+	if x != nil {
+		return x.Methods
+	}
+	return nil
+}
+
+type MethodRule struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	FullMethod string               `protobuf:"bytes,1,opt,name=full_method,json=fullMethod,proto3" json:"full_method,omitempty"`
+	Streaming  MethodRule_Streaming `protobuf:"varint,2,opt,name=streaming,proto3,enum=grappa.MethodRule_Streaming" json:"streaming,omitempty"`
+	Rule       *Rule                `protobuf:"bytes,3,opt,name=rule,proto3" json:"rule,omitempty"`
+	Source     string               `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`
+}
+
+func (x *MethodRule) Reset() {
+	*x = MethodRule{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_grappapb_annotations_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MethodRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MethodRule) ProtoMessage() {}
+
+func (x *MethodRule) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grappapb_annotations_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MethodRule.ProtoReflect.Descriptor instead.
+func (*MethodRule) Descriptor() ([]byte, []int) {
+	return file_proto_grappapb_annotations_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *MethodRule) GetFullMethod() string {
+	if x != nil {
+		return x.FullMethod
+	}
+	return ""
+}
+
+func (x *MethodRule) GetStreaming() MethodRule_Streaming {
+	if x != nil {
+		return x.Streaming
+	}
+	return MethodRule_UNARY
+}
+
+func (x *MethodRule) GetRule() *Rule {
+	if x != nil {
+		return x.Rule
+	}
+	return nil
+}
+
+func (x *MethodRule) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+type ClaimRequirement struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Claim    string                    `protobuf:"bytes,1,opt,name=claim,proto3" json:"claim,omitempty"`
+	Operator ClaimRequirement_Operator `protobuf:"varint,2,opt,name=operator,proto3,enum=grappa.ClaimRequirement_Operator" json:"operator,omitempty"`
+	Values   []string                  `protobuf:"bytes,3,rep,name=values,proto3" json:"values,omitempty"`
+}
+
+func (x *ClaimRequirement) Reset() {
+	*x = ClaimRequirement{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_grappapb_annotations_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClaimRequirement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClaimRequirement) ProtoMessage() {}
+
+func (x *ClaimRequirement) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grappapb_annotations_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClaimRequirement.ProtoReflect.Descriptor instead.
+func (*ClaimRequirement) Descriptor() ([]byte, []int) {
+	return file_proto_grappapb_annotations_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ClaimRequirement) GetClaim() string {
+	if x != nil {
+		return x.Claim
+	}
+	return ""
+}
+
+func (x *ClaimRequirement) GetOperator() ClaimRequirement_Operator {
+	if x != nil {
+		return x.Operator
+	}
+	return ClaimRequirement_EQUALS
+}
+
+func (x *ClaimRequirement) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type FieldBinding struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Field    string                `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	Claim    string                `protobuf:"bytes,2,opt,name=claim,proto3" json:"claim,omitempty"`
+	Operator FieldBinding_Operator `protobuf:"varint,3,opt,name=operator,proto3,enum=grappa.FieldBinding_Operator" json:"operator,omitempty"`
+}
+
+func (x *FieldBinding) Reset() {
+	*x = FieldBinding{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_grappapb_annotations_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FieldBinding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FieldBinding) ProtoMessage() {}
+
+func (x *FieldBinding) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_grappapb_annotations_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FieldBinding.ProtoReflect.Descriptor instead.
+func (*FieldBinding) Descriptor() ([]byte, []int) {
+	return file_proto_grappapb_annotations_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *FieldBinding) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *FieldBinding) GetClaim() string {
+	if x != nil {
+		return x.Claim
+	}
+	return ""
+}
+
+func (x *FieldBinding) GetOperator() FieldBinding_Operator {
+	if x != nil {
+		return x.Operator
+	}
+	return FieldBinding_EQUALS
+}
+
 var file_proto_grappapb_annotations_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.FileOptions)(nil),
+		ExtensionType: (*Rule)(nil),
+		Field:         15542659,
+		Name:          "grappa.file_rule",
+		Tag:           "bytes,15542659,opt,name=file_rule",
+		Filename:      "proto/grappapb/annotations.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.ServiceOptions)(nil),
+		ExtensionType: (*Rule)(nil),
+		Field:         15542659,
+		Name:          "grappa.service_rule",
+		Tag:           "bytes,15542659,opt,name=service_rule",
+		Filename:      "proto/grappapb/annotations.proto",
+	},
 	{
 		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
 		ExtensionType: (*Rule)(nil),
@@ -87,10 +553,22 @@ var file_proto_grappapb_annotations_proto_extTypes = []protoimpl.ExtensionInfo{
 	},
 }
 
+// Extension fields to descriptorpb.FileOptions.
+var (
+	// optional grappa.Rule file_rule = 15542659;
+	E_FileRule = &file_proto_grappapb_annotations_proto_extTypes[0]
+)
+
+// Extension fields to descriptorpb.ServiceOptions.
+var (
+	// optional grappa.Rule service_rule = 15542659;
+	E_ServiceRule = &file_proto_grappapb_annotations_proto_extTypes[1]
+)
+
 // Extension fields to descriptorpb.MethodOptions.
 var (
 	// optional grappa.Rule rule = 15542659;
-	E_Rule = &file_proto_grappapb_annotations_proto_extTypes[0]
+	E_Rule = &file_proto_grappapb_annotations_proto_extTypes[2]
 )
 
 var File_proto_grappapb_annotations_proto protoreflect.FileDescriptor
@@ -100,20 +578,94 @@ var file_proto_grappapb_annotations_proto_rawDesc = []byte{
 	0x2f, 0x61, 0x6e, 0x6e, 0x6f, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f,
 	0x74, 0x6f, 0x12, 0x06, 0x67, 0x72, 0x61, 0x70, 0x70, 0x61, 0x1a, 0x20, 0x67, 0x6f, 0x6f, 0x67,
 	0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x65, 0x73, 0x63,
-	0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x54, 0x0a, 0x04,
-	0x52, 0x75, 0x6c, 0x65, 0x12, 0x27, 0x0a, 0x0f, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x5f, 0x61, 0x6e,
-	0x6f, 0x6e, 0x79, 0x6d, 0x6f, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0e, 0x61,
-	0x6c, 0x6c, 0x6f, 0x77, 0x41, 0x6e, 0x6f, 0x6e, 0x79, 0x6d, 0x6f, 0x75, 0x73, 0x12, 0x23, 0x0a,
-	0x0d, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x5f, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x18, 0x02,
-	0x20, 0x03, 0x28, 0x09, 0x52, 0x0c, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x53, 0x63, 0x6f,
-	0x70, 0x65, 0x3a, 0x43, 0x0a, 0x04, 0x72, 0x75, 0x6c, 0x65, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f,
-	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74,
-	0x68, 0x6f, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x83, 0xd3, 0xb4, 0x07, 0x20,
+	0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xcc, 0x02, 0x0a,
+	0x04, 0x52, 0x75, 0x6c, 0x65, 0x12, 0x27, 0x0a, 0x0f, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x5f, 0x61,
+	0x6e, 0x6f, 0x6e, 0x79, 0x6d, 0x6f, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0e,
+	0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x41, 0x6e, 0x6f, 0x6e, 0x79, 0x6d, 0x6f, 0x75, 0x73, 0x12, 0x23,
+	0x0a, 0x0d, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x5f, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x18,
+	0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0c, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x53, 0x63,
+	0x6f, 0x70, 0x65, 0x12, 0x29, 0x0a, 0x10, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x5f, 0x65, 0x78, 0x70,
+	0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x73,
+	0x63, 0x6f, 0x70, 0x65, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x3d,
+	0x0a, 0x0d, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x5f, 0x63, 0x6c, 0x61, 0x69, 0x6d, 0x18,
+	0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x67, 0x72, 0x61, 0x70, 0x70, 0x61, 0x2e, 0x43,
+	0x6c, 0x61, 0x69, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x52,
+	0x0c, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x12, 0x21, 0x0a,
+	0x0c, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x5f, 0x72, 0x6f, 0x6c, 0x65, 0x18, 0x05, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x0b, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x52, 0x6f, 0x6c, 0x65,
+	0x12, 0x16, 0x0a, 0x06, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12, 0x33, 0x0a, 0x0a, 0x62, 0x69, 0x6e, 0x64,
+	0x5f, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x67,
+	0x72, 0x61, 0x70, 0x70, 0x61, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x42, 0x69, 0x6e, 0x64, 0x69,
+	0x6e, 0x67, 0x52, 0x09, 0x62, 0x69, 0x6e, 0x64, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x12, 0x1c, 0x0a,
+	0x09, 0x63, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x09, 0x63, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0xb1, 0x01, 0x0a, 0x07,
+	0x52, 0x75, 0x6c, 0x65, 0x53, 0x65, 0x74, 0x12, 0x30, 0x0a, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x72, 0x61, 0x70, 0x70, 0x61, 0x2e,
+	0x52, 0x75, 0x6c, 0x65, 0x53, 0x65, 0x74, 0x2e, 0x52, 0x75, 0x6c, 0x65, 0x73, 0x45, 0x6e, 0x74,
+	0x72, 0x79, 0x52, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x12, 0x2c, 0x0a, 0x07, 0x6d, 0x65, 0x74,
+	0x68, 0x6f, 0x64, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x67, 0x72, 0x61,
+	0x70, 0x70, 0x61, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x52, 0x75, 0x6c, 0x65, 0x52, 0x07,
+	0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x73, 0x1a, 0x46, 0x0a, 0x0a, 0x52, 0x75, 0x6c, 0x65, 0x73,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x22, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x67, 0x72, 0x61, 0x70, 0x70, 0x61, 0x2e,
+	0x52, 0x75, 0x6c, 0x65, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22,
+	0xfb, 0x01, 0x0a, 0x0a, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x52, 0x75, 0x6c, 0x65, 0x12, 0x1f,
+	0x0a, 0x0b, 0x66, 0x75, 0x6c, 0x6c, 0x5f, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0a, 0x66, 0x75, 0x6c, 0x6c, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x12,
+	0x3a, 0x0a, 0x09, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0e, 0x32, 0x1c, 0x2e, 0x67, 0x72, 0x61, 0x70, 0x70, 0x61, 0x2e, 0x4d, 0x65, 0x74, 0x68,
+	0x6f, 0x64, 0x52, 0x75, 0x6c, 0x65, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67,
+	0x52, 0x09, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x12, 0x20, 0x0a, 0x04, 0x72,
+	0x75, 0x6c, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x67, 0x72, 0x61, 0x70,
+	0x70, 0x61, 0x2e, 0x52, 0x75, 0x6c, 0x65, 0x52, 0x04, 0x72, 0x75, 0x6c, 0x65, 0x12, 0x16, 0x0a,
+	0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73,
+	0x6f, 0x75, 0x72, 0x63, 0x65, 0x22, 0x56, 0x0a, 0x09, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x69,
+	0x6e, 0x67, 0x12, 0x09, 0x0a, 0x05, 0x55, 0x4e, 0x41, 0x52, 0x59, 0x10, 0x00, 0x12, 0x14, 0x0a,
+	0x10, 0x43, 0x4c, 0x49, 0x45, 0x4e, 0x54, 0x5f, 0x53, 0x54, 0x52, 0x45, 0x41, 0x4d, 0x49, 0x4e,
+	0x47, 0x10, 0x01, 0x12, 0x14, 0x0a, 0x10, 0x53, 0x45, 0x52, 0x56, 0x45, 0x52, 0x5f, 0x53, 0x54,
+	0x52, 0x45, 0x41, 0x4d, 0x49, 0x4e, 0x47, 0x10, 0x02, 0x12, 0x12, 0x0a, 0x0e, 0x42, 0x49, 0x44,
+	0x49, 0x5f, 0x53, 0x54, 0x52, 0x45, 0x41, 0x4d, 0x49, 0x4e, 0x47, 0x10, 0x03, 0x22, 0xb7, 0x01,
+	0x0a, 0x10, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x6d, 0x65,
+	0x6e, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6c, 0x61, 0x69, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x63, 0x6c, 0x61, 0x69, 0x6d, 0x12, 0x3d, 0x0a, 0x08, 0x6f, 0x70, 0x65, 0x72,
+	0x61, 0x74, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x21, 0x2e, 0x67, 0x72, 0x61,
+	0x70, 0x70, 0x61, 0x2e, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65,
+	0x6d, 0x65, 0x6e, 0x74, 0x2e, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x52, 0x08, 0x6f,
+	0x70, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x22,
+	0x36, 0x0a, 0x08, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x12, 0x0a, 0x0a, 0x06, 0x45,
+	0x51, 0x55, 0x41, 0x4c, 0x53, 0x10, 0x00, 0x12, 0x06, 0x0a, 0x02, 0x49, 0x4e, 0x10, 0x01, 0x12,
+	0x0a, 0x0a, 0x06, 0x45, 0x58, 0x49, 0x53, 0x54, 0x53, 0x10, 0x02, 0x12, 0x0a, 0x0a, 0x06, 0x50,
+	0x52, 0x45, 0x46, 0x49, 0x58, 0x10, 0x03, 0x22, 0x95, 0x01, 0x0a, 0x0c, 0x46, 0x69, 0x65, 0x6c,
+	0x64, 0x42, 0x69, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x69, 0x65, 0x6c,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x12, 0x14,
+	0x0a, 0x05, 0x63, 0x6c, 0x61, 0x69, 0x6d, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x63,
+	0x6c, 0x61, 0x69, 0x6d, 0x12, 0x39, 0x0a, 0x08, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1d, 0x2e, 0x67, 0x72, 0x61, 0x70, 0x70, 0x61, 0x2e,
+	0x46, 0x69, 0x65, 0x6c, 0x64, 0x42, 0x69, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x2e, 0x4f, 0x70, 0x65,
+	0x72, 0x61, 0x74, 0x6f, 0x72, 0x52, 0x08, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x22,
+	0x1e, 0x0a, 0x08, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x12, 0x0a, 0x0a, 0x06, 0x45,
+	0x51, 0x55, 0x41, 0x4c, 0x53, 0x10, 0x00, 0x12, 0x06, 0x0a, 0x02, 0x49, 0x4e, 0x10, 0x01, 0x3a,
+	0x4a, 0x0a, 0x09, 0x66, 0x69, 0x6c, 0x65, 0x5f, 0x72, 0x75, 0x6c, 0x65, 0x12, 0x1c, 0x2e, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x46,
+	0x69, 0x6c, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x83, 0xd3, 0xb4, 0x07, 0x20,
 	0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x67, 0x72, 0x61, 0x70, 0x70, 0x61, 0x2e, 0x52, 0x75, 0x6c,
-	0x65, 0x52, 0x04, 0x72, 0x75, 0x6c, 0x65, 0x42, 0x2f, 0x5a, 0x2d, 0x67, 0x69, 0x74, 0x68, 0x75,
-	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x74, 0x65, 0x76, 0x65, 0x63, 0x61, 0x6c, 0x6c, 0x65,
-	0x61, 0x72, 0x2f, 0x67, 0x72, 0x61, 0x70, 0x70, 0x61, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f,
-	0x67, 0x72, 0x61, 0x70, 0x70, 0x61, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x65, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x52, 0x75, 0x6c, 0x65, 0x3a, 0x53, 0x0a, 0x0c, 0x73,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x72, 0x75, 0x6c, 0x65, 0x12, 0x1f, 0x2e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x83, 0xd3, 0xb4,
+	0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x67, 0x72, 0x61, 0x70, 0x70, 0x61, 0x2e, 0x52,
+	0x75, 0x6c, 0x65, 0x52, 0x0b, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x52, 0x75, 0x6c, 0x65,
+	0x3a, 0x43, 0x0a, 0x04, 0x72, 0x75, 0x6c, 0x65, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f,
+	0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x83, 0xd3, 0xb4, 0x07, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x0c, 0x2e, 0x67, 0x72, 0x61, 0x70, 0x70, 0x61, 0x2e, 0x52, 0x75, 0x6c, 0x65, 0x52,
+	0x04, 0x72, 0x75, 0x6c, 0x65, 0x42, 0x2f, 0x5a, 0x2d, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x74, 0x65, 0x76, 0x65, 0x63, 0x61, 0x6c, 0x6c, 0x65, 0x61, 0x72,
+	0x2f, 0x67, 0x72, 0x61, 0x70, 0x70, 0x61, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x67, 0x72,
+	0x61, 0x70, 0x70, 0x61, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -128,19 +680,43 @@ func file_proto_grappapb_annotations_proto_rawDescGZIP() []byte {
 	return file_proto_grappapb_annotations_proto_rawDescData
 }
 
-var file_proto_grappapb_annotations_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_proto_grappapb_annotations_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_proto_grappapb_annotations_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_proto_grappapb_annotations_proto_goTypes = []interface{}{
-	(*Rule)(nil),                       // 0: grappa.Rule
-	(*descriptorpb.MethodOptions)(nil), // 1: google.protobuf.MethodOptions
+	(MethodRule_Streaming)(0),           // 0: grappa.MethodRule.Streaming
+	(ClaimRequirement_Operator)(0),      // 1: grappa.ClaimRequirement.Operator
+	(FieldBinding_Operator)(0),          // 2: grappa.FieldBinding.Operator
+	(*Rule)(nil),                        // 3: grappa.Rule
+	(*RuleSet)(nil),                     // 4: grappa.RuleSet
+	(*MethodRule)(nil),                  // 5: grappa.MethodRule
+	(*ClaimRequirement)(nil),            // 6: grappa.ClaimRequirement
+	(*FieldBinding)(nil),                // 7: grappa.FieldBinding
+	nil,                                 // 8: grappa.RuleSet.RulesEntry
+	(*descriptorpb.FileOptions)(nil),    // 9: google.protobuf.FileOptions
+	(*descriptorpb.ServiceOptions)(nil), // 10: google.protobuf.ServiceOptions
+	(*descriptorpb.MethodOptions)(nil),  // 11: google.protobuf.MethodOptions
 }
 var file_proto_grappapb_annotations_proto_depIdxs = []int32{
-	1, // 0: grappa.rule:extendee -> google.protobuf.MethodOptions
-	0, // 1: grappa.rule:type_name -> grappa.Rule
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	1, // [1:2] is the sub-list for extension type_name
-	0, // [0:1] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	6,  // 0: grappa.Rule.require_claim:type_name -> grappa.ClaimRequirement
+	7,  // 1: grappa.Rule.bind_field:type_name -> grappa.FieldBinding
+	8,  // 2: grappa.RuleSet.rules:type_name -> grappa.RuleSet.RulesEntry
+	5,  // 3: grappa.RuleSet.methods:type_name -> grappa.MethodRule
+	0,  // 4: grappa.MethodRule.streaming:type_name -> grappa.MethodRule.Streaming
+	3,  // 5: grappa.MethodRule.rule:type_name -> grappa.Rule
+	1,  // 6: grappa.ClaimRequirement.operator:type_name -> grappa.ClaimRequirement.Operator
+	2,  // 7: grappa.FieldBinding.operator:type_name -> grappa.FieldBinding.Operator
+	3,  // 8: grappa.RuleSet.RulesEntry.value:type_name -> grappa.Rule
+	9,  // 9: grappa.file_rule:extendee -> google.protobuf.FileOptions
+	10, // 10: grappa.service_rule:extendee -> google.protobuf.ServiceOptions
+	11, // 11: grappa.rule:extendee -> google.protobuf.MethodOptions
+	3,  // 12: grappa.file_rule:type_name -> grappa.Rule
+	3,  // 13: grappa.service_rule:type_name -> grappa.Rule
+	3,  // 14: grappa.rule:type_name -> grappa.Rule
+	15, // [15:15] is the sub-list for method output_type
+	15, // [15:15] is the sub-list for method input_type
+	12, // [12:15] is the sub-list for extension type_name
+	9,  // [9:12] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
 }
 
 func init() { file_proto_grappapb_annotations_proto_init() }
@@ -161,19 +737,68 @@ func file_proto_grappapb_annotations_proto_init() {
 				return nil
 			}
 		}
+		file_proto_grappapb_annotations_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RuleSet); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_grappapb_annotations_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MethodRule); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_grappapb_annotations_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClaimRequirement); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_grappapb_annotations_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FieldBinding); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_proto_grappapb_annotations_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   1,
-			NumExtensions: 1,
+			NumEnums:      3,
+			NumMessages:   6,
+			NumExtensions: 3,
 			NumServices:   0,
 		},
 		GoTypes:           file_proto_grappapb_annotations_proto_goTypes,
 		DependencyIndexes: file_proto_grappapb_annotations_proto_depIdxs,
+		EnumInfos:         file_proto_grappapb_annotations_proto_enumTypes,
 		MessageInfos:      file_proto_grappapb_annotations_proto_msgTypes,
 		ExtensionInfos:    file_proto_grappapb_annotations_proto_extTypes,
 	}.Build()
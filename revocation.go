@@ -0,0 +1,247 @@
+package grappa
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+type (
+	// RevocationStore represents a token revocation store
+	RevocationStore interface {
+		// IsRevoked returns true if the token id has been revoked, or if tokens
+		// issued to the subject at the specified time have been revoked
+		// the issued at time is zero if the token does not have an iat claim
+		IsRevoked(jti, sub string, iat time.Time) (bool, error)
+	}
+
+	// MemoryRevocationStore represents an in-memory revocation store
+	// revocations are retained for the store ttl, which should be at least
+	// the maximum token lifetime
+	MemoryRevocationStore struct {
+		ttl      time.Duration
+		mu       sync.RWMutex
+		tokens   map[string]time.Time
+		subjects map[string]subjectRevocation
+	}
+
+	// FileRevocationStore represents a json file backed revocation store
+	// the file is reloaded when it changes, at most once per refresh interval
+	FileRevocationStore struct {
+		path     string
+		opts     FileRevocationOptions
+		mu       sync.Mutex
+		tokens   map[string]struct{}
+		subjects map[string]time.Time
+		modTime  time.Time
+		size     int64
+		checked  time.Time
+		checkErr error
+	}
+
+	// FileRevocationOptions represents a set of file revocation store options
+	FileRevocationOptions struct {
+		RefreshInterval time.Duration
+	}
+
+	subjectRevocation struct {
+		before time.Time
+		expiry time.Time
+	}
+
+	revocationList struct {
+		Tokens   []string             `json:"tokens"`
+		Subjects map[string]time.Time `json:"subjects"`
+	}
+)
+
+var defaultFileRevocationOptions = FileRevocationOptions{
+	RefreshInterval: 10 * time.Second,
+}
+
+// VerifyNotRevoked verifies that the token has not been revoked in the specified store
+func VerifyNotRevoked(s RevocationStore) VerifyFunc {
+	return func(_ Context, c jwt.MapClaims) error {
+		jti, _ := c["jti"].(string)
+		sub, _ := c["sub"].(string)
+		iat, _ := claimTime(c, "iat")
+
+		revoked, err := s.IsRevoked(jti, sub, iat)
+		if err != nil {
+			return err
+		}
+
+		if revoked {
+			return errors.New("token revoked")
+		}
+
+		return nil
+	}
+}
+
+// NewMemoryRevocationStore returns a new in-memory revocation store with the specified ttl
+func NewMemoryRevocationStore(ttl time.Duration) *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		ttl:      ttl,
+		tokens:   map[string]time.Time{},
+		subjects: map[string]subjectRevocation{},
+	}
+}
+
+// RevokeToken revokes the token with the specified id
+func (s *MemoryRevocationStore) RevokeToken(jti string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.purge(now)
+	s.tokens[jti] = now.Add(s.ttl)
+}
+
+// RevokeSubject revokes all tokens issued to the subject before the specified time
+func (s *MemoryRevocationStore) RevokeSubject(sub string, before time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.purge(now)
+
+	if r, ok := s.subjects[sub]; ok && r.before.After(before) {
+		before = r.before
+	}
+
+	s.subjects[sub] = subjectRevocation{
+		before: before,
+		expiry: before.Add(s.ttl),
+	}
+}
+
+// IsRevoked returns true if the token or subject has been revoked
+func (s *MemoryRevocationStore) IsRevoked(jti, sub string, iat time.Time) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	if exp, ok := s.tokens[jti]; ok && jti != "" && now.Before(exp) {
+		return true, nil
+	}
+
+	if r, ok := s.subjects[sub]; ok && sub != "" && now.Before(r.expiry) {
+		return iat.IsZero() || iat.Before(r.before), nil
+	}
+
+	return false, nil
+}
+
+func (s *MemoryRevocationStore) purge(now time.Time) {
+	for k, exp := range s.tokens {
+		if !now.Before(exp) {
+			delete(s.tokens, k)
+		}
+	}
+
+	for k, r := range s.subjects {
+		if !now.Before(r.expiry) {
+			delete(s.subjects, k)
+		}
+	}
+}
+
+// NewFileRevocationStore returns a new revocation store for the specified json file
+// the file contains revoked token ids and subject revocation times, e.g.
+// {"tokens": ["id"], "subjects": {"subject": "2021-08-01T00:00:00Z"}}
+// if the file cannot be read or parsed, then verification fails for all tokens
+func NewFileRevocationStore(path string, optFns ...func(*FileRevocationOptions)) (*FileRevocationStore, error) {
+	s := &FileRevocationStore{
+		path: path,
+		opts: defaultFileRevocationOptions,
+	}
+
+	for _, fn := range optFns {
+		fn(&s.opts)
+	}
+
+	if err := s.refresh(time.Now()); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// IsRevoked returns true if the token or subject has been revoked
+func (s *FileRevocationStore) IsRevoked(jti, sub string, iat time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := time.Now(); now.Sub(s.checked) >= s.opts.RefreshInterval {
+		s.checkErr = s.refresh(now)
+	}
+
+	if s.checkErr != nil {
+		return false, s.checkErr
+	}
+
+	if _, ok := s.tokens[jti]; ok && jti != "" {
+		return true, nil
+	}
+
+	if before, ok := s.subjects[sub]; ok && sub != "" {
+		return iat.IsZero() || iat.Before(before), nil
+	}
+
+	return false, nil
+}
+
+func (s *FileRevocationStore) refresh(now time.Time) error {
+	s.checked = now
+
+	fi, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+
+	if fi.ModTime().Equal(s.modTime) && fi.Size() == s.size {
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	var l revocationList
+	if err = json.Unmarshal(b, &l); err != nil {
+		return err
+	}
+
+	s.tokens = make(map[string]struct{}, len(l.Tokens))
+	for _, t := range l.Tokens {
+		s.tokens[t] = struct{}{}
+	}
+
+	s.subjects = l.Subjects
+	s.modTime = fi.ModTime()
+	s.size = fi.Size()
+
+	return nil
+}
+
+func claimTime(c jwt.MapClaims, name string) (time.Time, bool) {
+	switch v := c[name].(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.Unix(n, 0), true
+	default:
+		return time.Time{}, false
+	}
+}
@@ -0,0 +1,235 @@
+package grappa_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+
+	"github.com/stevecallear/grappa"
+)
+
+type revocationStoreFunc func(jti, sub string, iat time.Time) (bool, error)
+
+func (fn revocationStoreFunc) IsRevoked(jti, sub string, iat time.Time) (bool, error) {
+	return fn(jti, sub, iat)
+}
+
+func TestVerifyNotRevoked(t *testing.T) {
+	now := time.Now()
+
+	store := grappa.NewMemoryRevocationStore(time.Hour)
+	store.RevokeToken("revoked")
+	store.RevokeSubject("revoked", now)
+
+	tests := []struct {
+		name   string
+		store  grappa.RevocationStore
+		claims jwt.MapClaims
+		err    bool
+	}{
+		{
+			name: "should return an error if the store fails",
+			store: revocationStoreFunc(func(string, string, time.Time) (bool, error) {
+				return false, errors.New("error")
+			}),
+			claims: jwt.MapClaims{},
+			err:    true,
+		},
+		{
+			name:   "should return an error if the token id is revoked",
+			store:  store,
+			claims: jwt.MapClaims{"jti": "revoked", "sub": "subject"},
+			err:    true,
+		},
+		{
+			name:   "should return an error if the subject is revoked before the issue time",
+			store:  store,
+			claims: jwt.MapClaims{"jti": "id", "sub": "revoked", "iat": float64(now.Add(-time.Minute).Unix())},
+			err:    true,
+		},
+		{
+			name:   "should return an error if the subject is revoked and the issue time is not set",
+			store:  store,
+			claims: jwt.MapClaims{"jti": "id", "sub": "revoked"},
+			err:    true,
+		},
+		{
+			name:   "should not return an error if the subject is revoked after the issue time",
+			store:  store,
+			claims: jwt.MapClaims{"jti": "id", "sub": "revoked", "iat": float64(now.Add(time.Minute).Unix())},
+		},
+		{
+			name:   "should not return an error if the token is not revoked",
+			store:  store,
+			claims: jwt.MapClaims{"jti": "id", "sub": "subject", "iat": float64(now.Unix())},
+		},
+		{
+			name:   "should not return an error if the claims are not set",
+			store:  store,
+			claims: jwt.MapClaims{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := grappa.VerifyNotRevoked(tt.store)(grappa.Context{}, tt.claims)
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
+
+func TestMemoryRevocationStore(t *testing.T) {
+	tests := []struct {
+		name   string
+		ttl    time.Duration
+		revoke func(*grappa.MemoryRevocationStore)
+		jti    string
+		sub    string
+		iat    time.Time
+		exp    bool
+	}{
+		{
+			name: "should revoke token ids",
+			ttl:  time.Hour,
+			revoke: func(s *grappa.MemoryRevocationStore) {
+				s.RevokeToken("id")
+			},
+			jti: "id",
+			exp: true,
+		},
+		{
+			name: "should expire token ids after the ttl",
+			revoke: func(s *grappa.MemoryRevocationStore) {
+				s.RevokeToken("id")
+			},
+			jti: "id",
+		},
+		{
+			name: "should revoke subjects",
+			ttl:  time.Hour,
+			revoke: func(s *grappa.MemoryRevocationStore) {
+				s.RevokeSubject("subject", time.Now())
+			},
+			sub: "subject",
+			iat: time.Now().Add(-time.Minute),
+			exp: true,
+		},
+		{
+			name: "should retain the latest subject revocation",
+			ttl:  time.Hour,
+			revoke: func(s *grappa.MemoryRevocationStore) {
+				s.RevokeSubject("subject", time.Now())
+				s.RevokeSubject("subject", time.Now().Add(-time.Hour))
+			},
+			sub: "subject",
+			iat: time.Now().Add(-time.Minute),
+			exp: true,
+		},
+		{
+			name: "should expire subjects after the ttl",
+			ttl:  time.Minute,
+			revoke: func(s *grappa.MemoryRevocationStore) {
+				s.RevokeSubject("subject", time.Now().Add(-time.Hour))
+			},
+			sub: "subject",
+			iat: time.Now().Add(-2 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := grappa.NewMemoryRevocationStore(tt.ttl)
+			tt.revoke(sut)
+
+			act, err := sut.IsRevoked(tt.jti, tt.sub, tt.iat)
+			assertErrorExists(t, err, false)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestFileRevocationStore(t *testing.T) {
+	const list = `{"tokens": ["revoked"], "subjects": {"revoked": "2021-08-01T00:00:00Z"}}`
+
+	iat := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("should return an error if the file does not exist", func(t *testing.T) {
+		_, err := grappa.NewFileRevocationStore(filepath.Join(t.TempDir(), "revoked.json"))
+		assertErrorExists(t, err, true)
+	})
+
+	t.Run("should return an error if the file is invalid", func(t *testing.T) {
+		path := writeRevocationList(t, filepath.Join(t.TempDir(), "revoked.json"), `{`)
+
+		_, err := grappa.NewFileRevocationStore(path)
+		assertErrorExists(t, err, true)
+	})
+
+	t.Run("should return revocations", func(t *testing.T) {
+		path := writeRevocationList(t, filepath.Join(t.TempDir(), "revoked.json"), list)
+
+		sut, err := grappa.NewFileRevocationStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tt := range []struct {
+			jti string
+			sub string
+			iat time.Time
+			exp bool
+		}{
+			{jti: "revoked", sub: "subject", exp: true},
+			{jti: "id", sub: "revoked", iat: iat, exp: true},
+			{jti: "id", sub: "revoked", iat: iat.AddDate(0, 2, 0), exp: false},
+			{jti: "id", sub: "subject", iat: iat, exp: false},
+		} {
+			act, err := sut.IsRevoked(tt.jti, tt.sub, tt.iat)
+			assertErrorExists(t, err, false)
+			assertDeepEqual(t, act, tt.exp)
+		}
+	})
+
+	t.Run("should reload the file when it changes", func(t *testing.T) {
+		path := writeRevocationList(t, filepath.Join(t.TempDir(), "revoked.json"), list)
+
+		sut, err := grappa.NewFileRevocationStore(path, func(o *grappa.FileRevocationOptions) {
+			o.RefreshInterval = 0
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		writeRevocationList(t, path, `{"tokens": ["other"]}`)
+		mt := time.Now().Add(time.Minute)
+		if err = os.Chtimes(path, mt, mt); err != nil {
+			t.Fatal(err)
+		}
+
+		act, err := sut.IsRevoked("other", "", time.Time{})
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, act, true)
+
+		writeRevocationList(t, path, `{`)
+		mt = mt.Add(time.Minute)
+		if err = os.Chtimes(path, mt, mt); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = sut.IsRevoked("other", "", time.Time{})
+		assertErrorExists(t, err, true)
+	})
+}
+
+func writeRevocationList(t *testing.T, path, content string) string {
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
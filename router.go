@@ -0,0 +1,149 @@
+package grappa
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/stevecallear/grappa/internal/scope"
+	"github.com/stevecallear/grappa/proto/grappapb"
+)
+
+type (
+	// router represents a compiled set of rules
+	// exact patterns take precedence over wildcard patterns, and longer
+	// wildcard prefixes take precedence over shorter ones
+	router struct {
+		exact  map[string]*rule
+		prefix *node
+	}
+
+	node struct {
+		children map[byte]*node
+		rule     *rule
+	}
+
+	rule struct {
+		pattern   string
+		rule      *grappapb.Rule
+		scope     scope.Expr
+		condition cel.Program
+	}
+)
+
+func newRouter() *router {
+	return &router{
+		exact:  map[string]*rule{},
+		prefix: new(node),
+	}
+}
+
+// add adds the rule for its pattern, returning an error if a different
+// rule is already registered for the same pattern
+func (r *router) add(nr *rule) error {
+	key := strings.ToLower(nr.pattern)
+	if strings.HasSuffix(key, "*") {
+		n := r.prefix
+		for _, c := range []byte(key[:len(key)-1]) {
+			if n.children == nil {
+				n.children = map[byte]*node{}
+			}
+
+			cn, ok := n.children[c]
+			if !ok {
+				cn = new(node)
+				n.children[c] = cn
+			}
+			n = cn
+		}
+
+		if err := conflict(n.rule, nr); err != nil {
+			return err
+		}
+
+		n.rule = nr
+		return nil
+	}
+
+	if err := conflict(r.exact[key], nr); err != nil {
+		return err
+	}
+
+	r.exact[key] = nr
+	return nil
+}
+
+// remove removes the rule for the specified pattern
+func (r *router) remove(pattern string) {
+	key := strings.ToLower(pattern)
+	if !strings.HasSuffix(key, "*") {
+		delete(r.exact, key)
+		return
+	}
+
+	n := r.prefix
+	for _, c := range []byte(key[:len(key)-1]) {
+		if n = n.children[c]; n == nil {
+			return
+		}
+	}
+
+	n.rule = nil
+}
+
+// clone returns a copy of the router that can be modified independently
+func (r *router) clone() *router {
+	c := &router{
+		exact:  make(map[string]*rule, len(r.exact)),
+		prefix: r.prefix.clone(),
+	}
+
+	for k, v := range r.exact {
+		c.exact[k] = v
+	}
+
+	return c
+}
+
+func (n *node) clone() *node {
+	c := &node{rule: n.rule}
+	if n.children != nil {
+		c.children = make(map[byte]*node, len(n.children))
+		for k, v := range n.children {
+			c.children[k] = v.clone()
+		}
+	}
+
+	return c
+}
+
+// match returns the most specific rule for the method
+func (r *router) match(fullMethod string) (*rule, bool) {
+	key := strings.ToLower(fullMethod)
+	if er, ok := r.exact[key]; ok {
+		return er, true
+	}
+
+	n, m := r.prefix, r.prefix.rule
+	for i := 0; i < len(key); i++ {
+		if n = n.children[key[i]]; n == nil {
+			break
+		}
+
+		if n.rule != nil {
+			m = n.rule
+		}
+	}
+
+	return m, m != nil
+}
+
+func conflict(existing, r *rule) error {
+	if existing == nil || proto.Equal(existing.rule, r.rule) {
+		return nil
+	}
+
+	return fmt.Errorf("conflicting rule for pattern %s: already registered as %s", r.pattern, existing.pattern)
+}